@@ -0,0 +1,125 @@
+package main
+
+import (
+	"crypto/subtle"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/discord"
+	"github.com/kolesaev/alertmanager-discord/logging"
+	"github.com/kolesaev/alertmanager-discord/windows"
+)
+
+// registerAdminRoutes adds the admin API, protected by the admin token. It's
+// not registered at all when no token is configured
+func registerAdminRoutes(router *gin.Engine, configs config.Config) {
+	if token, _ := configs.Admin.GetToken(); token == "" {
+		return
+	}
+
+	admin := router.Group("/admin", requireAdminToken(configs.Admin))
+
+	admin.POST("/test/:channel", func(c *gin.Context) {
+		channelName := c.Param("channel")
+
+		if _, ok := configs.DiscordChannels[channelName]; !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown channel " + channelName})
+			return
+		}
+
+		var options alertmanager.SyntheticOptions
+		if err := c.ShouldBindJSON(&options); err != nil && err != io.EOF {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		body, err := alertmanager.NewSyntheticMessageBody(options, configs.Severity.Label, channelName)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// The synthetic alerts go through the normal pipeline
+		ctx := c.Request.Context()
+		result, err := discord.SendAlerts(ctx, channelName, body, configs)
+		if err != nil {
+			logging.FromContext(ctx).Error("Error sending test alert", "channel", channelName, "error", err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "discord": result})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"discord": result})
+	})
+
+	admin.GET("/windows", func(c *gin.Context) {
+		c.JSON(http.StatusOK, windows.Default.List(c.Query("channel")))
+	})
+
+	admin.POST("/windows/:channel", func(c *gin.Context) {
+		channelName := c.Param("channel")
+
+		if _, ok := configs.DiscordChannels[channelName]; !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown channel " + channelName})
+			return
+		}
+
+		var timeWindow config.TimeWindow
+		if err := c.ShouldBindJSON(&timeWindow); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		timeWindow.Severities = configs.Severity.NormalizeAll(timeWindow.Severities)
+		window, err := windows.Default.Add(channelName, timeWindow)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		logging.FromContext(c.Request.Context()).Info("Added time window",
+			"channel", channelName, "id", window.ID, "window", window.Name, "action", window.Action)
+		c.JSON(http.StatusCreated, window)
+	})
+
+	admin.DELETE("/windows/:id", func(c *gin.Context) {
+		id := c.Param("id")
+
+		if !windows.Default.Remove(id) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown window " + id})
+			return
+		}
+
+		logging.FromContext(c.Request.Context()).Info("Removed time window", "id", id)
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// requireAdminToken rejects requests without the admin token, given as
+// bearer or, so browsers can prompt for it, as the password of HTTP basic
+// auth. The token is read on each request, so a mounted token file can be
+// rotated
+func requireAdminToken(adminConfig config.AdminConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := adminConfig.GetToken()
+		if err != nil || token == "" {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "admin token unavailable"})
+			return
+		}
+
+		given := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if _, password, ok := c.Request.BasicAuth(); ok {
+			given = password
+		}
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Basic realm="alertmanager-discord"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		c.Next()
+	}
+}
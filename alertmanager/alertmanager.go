@@ -1,12 +1,39 @@
 package alertmanager
 
 import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/logging"
+	"github.com/kolesaev/alertmanager-discord/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Special values for the groupBy list
+const (
+	// GroupByAlert sends each alert in its own embed
+	GroupByAlert = "..."
+	// GroupByNone puts all alerts of a status in a single embed
+	GroupByNone = "none"
 )
 
 // ExtractBodyInfo Extracts the necessary info to perform the checks and construct the Discord
-// message body more easily
-func ExtractBodyInfo(alertmanagerBody MessageBody, config config.Config) MessageBodyInfo {
+// message body more easily. Alerts are grouped by the values of the groupBy labels
+func ExtractBodyInfo(
+	ctx context.Context,
+	alertmanagerBody MessageBody,
+	groupBy []string,
+	config config.Config) MessageBodyInfo {
+
+	ctx, span := tracing.Start(ctx, "alertmanager.ExtractBodyInfo")
+	defer span.End()
+
+	logger := logging.FromContext(ctx)
 
 	alerts := alertmanagerBody.Alerts
 
@@ -16,38 +43,49 @@ func ExtractBodyInfo(alertmanagerBody MessageBody, config config.Config) Message
 	firingAlertsGroupedByName := AlertsGroupedByLabel{}
 	resolvedAlertsGroupedByName := AlertsGroupedByLabel{}
 
-	alertName := ""
-
-	for _, alert := range alerts {
-		alertName = alert.Labels["alertname"]
+	for i, alert := range alerts {
+		groupKey, groupKeyLabels := getGroupKey(alert, i, groupBy)
 		status := alert.Status
 
-		severityValue, ok := alert.Labels[config.Severity.Label]
-		if ok {
-			countBySeverity[severityValue]++
-		} else {
-			alert.Labels[config.Severity.Label] = "unknown"
-			countBySeverity["unknown"]++
-		}
+		alert.Severity = config.Severity.Of(alert.Labels)
+		countBySeverity[alert.Severity]++
 
 		if status == "firing" {
 			firingCount++
-			group := firingAlertsGroupedByName[alertName]
+			group := firingAlertsGroupedByName[groupKey]
 			group.Alerts = append(group.Alerts, alert)
 			group.GroupLabels = alertmanagerBody.GroupLabels
-			firingAlertsGroupedByName[alertName] = group
+			group.KeyLabels = groupKeyLabels
+			firingAlertsGroupedByName[groupKey] = group
 		} else if status == "resolved" {
 			resolvedCount++
-			group := resolvedAlertsGroupedByName[alertName]
+			group := resolvedAlertsGroupedByName[groupKey]
 			group.Alerts = append(group.Alerts, alert)
 			group.GroupLabels = alertmanagerBody.GroupLabels
-			resolvedAlertsGroupedByName[alertName] = group
+			group.KeyLabels = groupKeyLabels
+			resolvedAlertsGroupedByName[groupKey] = group
+		} else {
+			logger.Warn("Skipping alert with unknown status",
+				"status", status, "alertname", alert.Labels["alertname"])
 		}
 	}
 
+	logger.Debug("Extracted alerts",
+		"firingCount", firingCount,
+		"resolvedCount", resolvedCount,
+		"truncatedCount", alertmanagerBody.TruncatedAlerts,
+		"firingGroups", len(firingAlertsGroupedByName),
+		"resolvedGroups", len(resolvedAlertsGroupedByName))
+
+	span.SetAttributes(
+		attribute.Int("alerts.firing", firingCount),
+		attribute.Int("alerts.resolved", resolvedCount),
+		attribute.Int("alerts.truncated", int(alertmanagerBody.TruncatedAlerts)))
+
 	return MessageBodyInfo{
 		FiringCount:                 firingCount,
 		ResolvedCount:               resolvedCount,
+		TruncatedCount:              int(alertmanagerBody.TruncatedAlerts),
 		CountBySeverity:             countBySeverity,
 		FiringAlertsGroupedByName:   firingAlertsGroupedByName,
 		ResolvedAlertsGroupedByName: resolvedAlertsGroupedByName,
@@ -55,35 +93,63 @@ func ExtractBodyInfo(alertmanagerBody MessageBody, config config.Config) Message
 		CommonLabels:                alertmanagerBody.CommonLabels,
 		CommonAnnotations:           alertmanagerBody.CommonAnnotations,
 		ExternalURL:                 alertmanagerBody.ExternalURL,
+		Receiver:                    alertmanagerBody.Receiver,
 	}
 }
 
-// CheckIfHasOnlySeveritiesToIgnoreWhenAlone verifies if in the countBySeverity
-// map there are only severities that should be ignored when alone. It first
-// tries to use the array "SeveritiesToIgnoreWhenAlone" defined in the Discord
-// Channel, then in the global config, and if there isn't any, returns false.
-func CheckIfHasOnlySeveritiesToIgnoreWhenAlone(
-	countBySeverity map[string]int,
-	discordChannel config.DiscordChannel,
-	configs config.Config) bool {
-
-	var severitiesToIgnore []string
-
-	if len(discordChannel.SeveritiesToIgnoreWhenAlone) > 0 {
-		severitiesToIgnore = discordChannel.SeveritiesToIgnoreWhenAlone
-	} else if len(configs.SeveritiesToIgnoreWhenAlone) > 0 {
-		severitiesToIgnore = configs.SeveritiesToIgnoreWhenAlone
-	} else {
-		return false
+// GetAlertsURL returns the link to Alertmanager's UI listing the active
+// alerts matching the given labels, e.g. the group labels of a notification
+func GetAlertsURL(externalURL string, labels map[string]string, receiver string) string {
+	if externalURL == "" {
+		return ""
 	}
 
-	for severity := range countBySeverity {
-		if !contains(severitiesToIgnore, severity) {
-			return false
+	labelNames := make([]string, 0, len(labels))
+	for labelName := range labels {
+		labelNames = append(labelNames, labelName)
+	}
+	sort.Strings(labelNames)
+
+	matchers := make([]string, 0, len(labelNames))
+	for _, labelName := range labelNames {
+		matchers = append(matchers, fmt.Sprintf("%s=%q", labelName, labels[labelName]))
+	}
+
+	query := url.Values{}
+	query.Set("silenced", "false")
+	query.Set("inhibited", "false")
+	query.Set("active", "true")
+	query.Set("filter", "{"+strings.Join(matchers, ",")+"}")
+	if receiver != "" {
+		query.Set("receiver", receiver)
+	}
+
+	return strings.TrimSuffix(externalURL, "/") + "/#/alerts?" + query.Encode()
+}
+
+// getGroupKey builds the composite key of the group an alert belongs to,
+// e.g. `alertname="Foo",namespace="bar"`, and the labels it is made of
+func getGroupKey(alert Alert, index int, groupBy []string) (string, map[string]string) {
+	if contains(groupBy, GroupByNone) {
+		return "", map[string]string{}
+	}
+
+	if contains(groupBy, GroupByAlert) {
+		if alert.Fingerprint != "" {
+			return alert.Fingerprint, alert.Labels
 		}
+		return strconv.Itoa(index), alert.Labels
+	}
+
+	keyLabels := make(map[string]string, len(groupBy))
+	keyParts := make([]string, 0, len(groupBy))
+	for _, labelName := range groupBy {
+		labelValue := alert.Labels[labelName]
+		keyLabels[labelName] = labelValue
+		keyParts = append(keyParts, fmt.Sprintf("%s=%q", labelName, labelValue))
 	}
 
-	return true
+	return strings.Join(keyParts, ","), keyLabels
 }
 
 func contains(s []string, str string) bool {
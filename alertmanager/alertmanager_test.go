@@ -0,0 +1,115 @@
+package alertmanager
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+func TestGetGroupKey(t *testing.T) {
+	alert := Alert{
+		Fingerprint: "f1",
+		Labels:      map[string]string{"alertname": "HighLatency", "namespace": "prod"},
+	}
+
+	tests := []struct {
+		name          string
+		alert         Alert
+		groupBy       []string
+		wantKey       string
+		wantKeyLabels map[string]string
+	}{
+		{"alertname", alert, []string{"alertname"}, `alertname="HighLatency"`,
+			map[string]string{"alertname": "HighLatency"}},
+		{"several labels", alert, []string{"alertname", "namespace"}, `alertname="HighLatency",namespace="prod"`,
+			map[string]string{"alertname": "HighLatency", "namespace": "prod"}},
+		{"missing label", alert, []string{"cluster"}, `cluster=""`, map[string]string{"cluster": ""}},
+		{"each alert", alert, []string{GroupByAlert}, "f1", alert.Labels},
+		{"each alert without fingerprint", Alert{Labels: alert.Labels}, []string{GroupByAlert}, "3", alert.Labels},
+		{"none", alert, []string{GroupByNone}, "", map[string]string{}},
+		{"none wins", alert, []string{"alertname", GroupByNone}, "", map[string]string{}},
+		{"no labels", alert, nil, "", map[string]string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key, keyLabels := getGroupKey(test.alert, 3, test.groupBy)
+			if key != test.wantKey {
+				t.Errorf("getGroupKey() key = %q, want %q", key, test.wantKey)
+			}
+			if !reflect.DeepEqual(keyLabels, test.wantKeyLabels) {
+				t.Errorf("getGroupKey() labels = %v, want %v", keyLabels, test.wantKeyLabels)
+			}
+		})
+	}
+}
+
+func TestExtractBodyInfoGroups(t *testing.T) {
+	body := MessageBody{Alerts: []Alert{
+		{Status: "firing", Labels: map[string]string{"alertname": "HighLatency", "namespace": "prod"}},
+		{Status: "firing", Labels: map[string]string{"alertname": "HighLatency", "namespace": "staging"}},
+		{Status: "firing", Labels: map[string]string{"alertname": "HighErrorRate", "namespace": "prod"}},
+		{Status: "resolved", Labels: map[string]string{"alertname": "HighLatency", "namespace": "prod"}},
+	}}
+
+	tests := []struct {
+		name               string
+		groupBy            []string
+		wantFiringGroups   int
+		wantResolvedGroups int
+	}{
+		{"alertname", []string{"alertname"}, 2, 1},
+		{"namespace", []string{"namespace"}, 2, 1},
+		{"alertname and namespace", []string{"alertname", "namespace"}, 3, 1},
+		{"each alert", []string{GroupByAlert}, 3, 1},
+		{"none", []string{GroupByNone}, 1, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info := ExtractBodyInfo(context.Background(), body, test.groupBy, config.Config{})
+
+			if len(info.FiringAlertsGroupedByName) != test.wantFiringGroups ||
+				len(info.ResolvedAlertsGroupedByName) != test.wantResolvedGroups {
+				t.Errorf("%d firing and %d resolved groups, want %d and %d",
+					len(info.FiringAlertsGroupedByName), len(info.ResolvedAlertsGroupedByName),
+					test.wantFiringGroups, test.wantResolvedGroups)
+			}
+			if info.FiringCount != 3 || info.ResolvedCount != 1 {
+				t.Errorf("%d firing and %d resolved alerts, want 3 and 1", info.FiringCount, info.ResolvedCount)
+			}
+		})
+	}
+}
+
+func TestGetAlertsURL(t *testing.T) {
+	labels := map[string]string{"namespace": "prod", "alertname": "HighLatency"}
+
+	tests := []struct {
+		name        string
+		externalURL string
+		labels      map[string]string
+		receiver    string
+		want        string
+	}{
+		{"no external URL", "", labels, "discord", ""},
+		{"sorted matchers", "http://alertmanager:9093", labels, "",
+			"http://alertmanager:9093/#/alerts?active=true&filter=%7Balertname%3D%22HighLatency%22%2Cnamespace%3D%22prod%22%7D&inhibited=false&silenced=false"},
+		{"trailing slash", "http://alertmanager:9093/", map[string]string{"alertname": "HighLatency"}, "",
+			"http://alertmanager:9093/#/alerts?active=true&filter=%7Balertname%3D%22HighLatency%22%7D&inhibited=false&silenced=false"},
+		{"receiver", "http://alertmanager:9093", map[string]string{"alertname": "HighLatency"}, "discord",
+			"http://alertmanager:9093/#/alerts?active=true&filter=%7Balertname%3D%22HighLatency%22%7D&inhibited=false&receiver=discord&silenced=false"},
+		{"no labels", "http://alertmanager:9093", nil, "",
+			"http://alertmanager:9093/#/alerts?active=true&filter=%7B%7D&inhibited=false&silenced=false"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := GetAlertsURL(test.externalURL, test.labels, test.receiver); got != test.want {
+				t.Errorf("GetAlertsURL() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
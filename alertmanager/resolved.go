@@ -0,0 +1,72 @@
+package alertmanager
+
+import (
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+// Resolved policies of the channels
+const (
+	ResolvedAll        = "all"
+	ResolvedNone       = "none"
+	ResolvedSeverities = "severities"
+	ResolvedCollapsed  = "collapsed"
+)
+
+// ApplyResolvedPolicy drops the resolved alerts the channel doesn't want, or
+// collapses them into CollapsedResolvedCount, and returns how many were
+// dropped. Dropped and collapsed alerts are removed from the severity count
+// too, so it only counts the alerts shown in the message
+func ApplyResolvedPolicy(
+	alertmanagerBodyInfo *MessageBodyInfo,
+	policy config.ResolvedPolicy) int {
+
+	switch policy.Send {
+	case ResolvedCollapsed:
+		for _, group := range alertmanagerBodyInfo.ResolvedAlertsGroupedByName {
+			for _, alert := range group.Alerts {
+				uncountSeverity(alertmanagerBodyInfo, alert.Severity)
+			}
+		}
+		alertmanagerBodyInfo.CollapsedResolvedCount = alertmanagerBodyInfo.ResolvedCount
+		alertmanagerBodyInfo.ResolvedCount = 0
+		alertmanagerBodyInfo.ResolvedAlertsGroupedByName = AlertsGroupedByLabel{}
+		return 0
+	case ResolvedNone, ResolvedSeverities:
+	default:
+		return 0
+	}
+
+	dropped := 0
+	kept := AlertsGroupedByLabel{}
+
+	for groupKey, group := range alertmanagerBodyInfo.ResolvedAlertsGroupedByName {
+		var alerts []Alert
+		for _, alert := range group.Alerts {
+			severity := alert.Severity
+			if policy.Send == ResolvedSeverities && contains(policy.Severities, severity) {
+				alerts = append(alerts, alert)
+				continue
+			}
+
+			dropped++
+			uncountSeverity(alertmanagerBodyInfo, severity)
+		}
+
+		if len(alerts) > 0 {
+			group.Alerts = alerts
+			kept[groupKey] = group
+		}
+	}
+
+	alertmanagerBodyInfo.ResolvedAlertsGroupedByName = kept
+	alertmanagerBodyInfo.ResolvedCount -= dropped
+
+	return dropped
+}
+
+func uncountSeverity(alertmanagerBodyInfo *MessageBodyInfo, severity string) {
+	alertmanagerBodyInfo.CountBySeverity[severity]--
+	if alertmanagerBodyInfo.CountBySeverity[severity] <= 0 {
+		delete(alertmanagerBodyInfo.CountBySeverity, severity)
+	}
+}
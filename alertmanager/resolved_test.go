@@ -0,0 +1,97 @@
+package alertmanager
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+func TestApplyResolvedPolicy(t *testing.T) {
+	// One firing critical alert, and resolved critical and warning ones
+	newBodyInfo := func() MessageBodyInfo {
+		return MessageBodyInfo{
+			FiringCount:     1,
+			ResolvedCount:   3,
+			CountBySeverity: map[string]int{"critical": 2, "warning": 2},
+			FiringAlertsGroupedByName: AlertsGroupedByLabel{
+				"HighLatency": {Alerts: []Alert{{Status: "firing", Severity: "critical"}}},
+			},
+			ResolvedAlertsGroupedByName: AlertsGroupedByLabel{
+				"HighLatency":   {Alerts: []Alert{{Status: "resolved", Severity: "critical"}}},
+				"HighErrorRate": {Alerts: []Alert{{Status: "resolved", Severity: "warning"}, {Status: "resolved", Severity: "warning"}}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name                string
+		policy              config.ResolvedPolicy
+		wantDropped         int
+		wantResolvedCount   int
+		wantCollapsedCount  int
+		wantResolvedGroups  int
+		wantCountBySeverity map[string]int
+	}{
+		{
+			name:                "default sends all",
+			policy:              config.ResolvedPolicy{},
+			wantResolvedCount:   3,
+			wantResolvedGroups:  2,
+			wantCountBySeverity: map[string]int{"critical": 2, "warning": 2},
+		},
+		{
+			name:                "all",
+			policy:              config.ResolvedPolicy{Send: ResolvedAll},
+			wantResolvedCount:   3,
+			wantResolvedGroups:  2,
+			wantCountBySeverity: map[string]int{"critical": 2, "warning": 2},
+		},
+		{
+			name:                "none",
+			policy:              config.ResolvedPolicy{Send: ResolvedNone},
+			wantDropped:         3,
+			wantCountBySeverity: map[string]int{"critical": 1},
+		},
+		{
+			name:                "severities",
+			policy:              config.ResolvedPolicy{Send: ResolvedSeverities, Severities: []string{"critical"}},
+			wantDropped:         2,
+			wantResolvedCount:   1,
+			wantResolvedGroups:  1,
+			wantCountBySeverity: map[string]int{"critical": 2},
+		},
+		{
+			name:                "collapsed",
+			policy:              config.ResolvedPolicy{Send: ResolvedCollapsed},
+			wantCollapsedCount:  3,
+			wantCountBySeverity: map[string]int{"critical": 1},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info := newBodyInfo()
+			dropped := ApplyResolvedPolicy(&info, test.policy)
+
+			if dropped != test.wantDropped {
+				t.Errorf("dropped = %d, want %d", dropped, test.wantDropped)
+			}
+			if info.ResolvedCount != test.wantResolvedCount {
+				t.Errorf("ResolvedCount = %d, want %d", info.ResolvedCount, test.wantResolvedCount)
+			}
+			if info.CollapsedResolvedCount != test.wantCollapsedCount {
+				t.Errorf("CollapsedResolvedCount = %d, want %d", info.CollapsedResolvedCount, test.wantCollapsedCount)
+			}
+			if len(info.ResolvedAlertsGroupedByName) != test.wantResolvedGroups {
+				t.Errorf("%d resolved groups, want %d", len(info.ResolvedAlertsGroupedByName), test.wantResolvedGroups)
+			}
+			if !reflect.DeepEqual(info.CountBySeverity, test.wantCountBySeverity) {
+				t.Errorf("CountBySeverity = %v, want %v", info.CountBySeverity, test.wantCountBySeverity)
+			}
+			if info.FiringCount != 1 || len(info.FiringAlertsGroupedByName) != 1 {
+				t.Errorf("Firing alerts changed")
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package alertmanager
+
+import (
+	"fmt"
+	"time"
+)
+
+// SyntheticOptions defines the alerts of a synthetic notification
+type SyntheticOptions struct {
+	AlertName string `json:"alertname"`
+	Severity  string `json:"severity"`
+	// "firing" or "resolved"
+	Status string `json:"status"`
+	Count  int    `json:"count"`
+}
+
+// maxSyntheticAlerts keeps test notifications within what a real group
+// would hold
+const maxSyntheticAlerts = 100
+
+// NewSyntheticMessageBody builds a notification like the ones sent by
+// Alertmanager, to test a channel end to end. The severity is set in
+// severityLabel
+func NewSyntheticMessageBody(options SyntheticOptions, severityLabel, receiver string) (MessageBody, error) {
+	if options.AlertName == "" {
+		options.AlertName = "TestAlert"
+	}
+	if options.Status == "" {
+		options.Status = "firing"
+	}
+	if options.Count == 0 {
+		options.Count = 1
+	}
+
+	if options.Status != "firing" && options.Status != "resolved" {
+		return MessageBody{}, fmt.Errorf(
+			"alertmanager.NewSyntheticMessageBody: Invalid status %q, should be firing or resolved", options.Status)
+	}
+	if options.Count < 0 || options.Count > maxSyntheticAlerts {
+		return MessageBody{}, fmt.Errorf(
+			"alertmanager.NewSyntheticMessageBody: Invalid count %d, should be between 1 and %d",
+			options.Count, maxSyntheticAlerts)
+	}
+
+	now := time.Now().UTC()
+	startsAt := now.Add(-5 * time.Minute)
+
+	alerts := make([]Alert, options.Count)
+	for i := range alerts {
+		labels := map[string]string{
+			"alertname": options.AlertName,
+			"instance":  fmt.Sprintf("test-instance-%d", i+1),
+		}
+		if options.Severity != "" {
+			labels[severityLabel] = options.Severity
+		}
+
+		alert := Alert{
+			Status: options.Status,
+			Labels: labels,
+			Annotations: map[string]string{
+				"summary":     "Test alert sent by alertmanager-discord",
+				"description": fmt.Sprintf("Synthetic alert %d of %d, sent to test the channel", i+1, options.Count),
+			},
+			StartsAt:    startsAt.Format(time.RFC3339),
+			EndsAt:      "0001-01-01T00:00:00Z",
+			Fingerprint: fmt.Sprintf("test%012d", i+1),
+		}
+		if options.Status == "resolved" {
+			alert.EndsAt = now.Format(time.RFC3339)
+		}
+
+		alerts[i] = alert
+	}
+
+	commonLabels := map[string]string{"alertname": options.AlertName}
+	if options.Severity != "" {
+		commonLabels[severityLabel] = options.Severity
+	}
+
+	return MessageBody{
+		Receiver:          receiver,
+		Status:            options.Status,
+		Alerts:            alerts,
+		GroupLabels:       map[string]string{"alertname": options.AlertName},
+		CommonLabels:      commonLabels,
+		CommonAnnotations: map[string]string{"summary": "Test alert sent by alertmanager-discord"},
+		Version:           "4",
+		GroupKey:          fmt.Sprintf(`{}:{alertname=%q}`, options.AlertName),
+	}, nil
+}
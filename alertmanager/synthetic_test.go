@@ -0,0 +1,78 @@
+package alertmanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewSyntheticMessageBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       SyntheticOptions
+		wantAlerts    int
+		wantAlertName string
+		wantStatus    string
+		wantErr       bool
+	}{
+		{"defaults", SyntheticOptions{}, 1, "TestAlert", "firing", false},
+		{"options", SyntheticOptions{AlertName: "HighLatency", Severity: "critical", Status: "resolved", Count: 3},
+			3, "HighLatency", "resolved", false},
+		{"most alerts", SyntheticOptions{Count: maxSyntheticAlerts}, maxSyntheticAlerts, "TestAlert", "firing", false},
+		{"invalid status", SyntheticOptions{Status: "pending"}, 0, "", "", true},
+		{"negative count", SyntheticOptions{Count: -1}, 0, "", "", true},
+		{"too many alerts", SyntheticOptions{Count: maxSyntheticAlerts + 1}, 0, "", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body, err := NewSyntheticMessageBody(test.options, "level", "discord")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("NewSyntheticMessageBody() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+
+			if len(body.Alerts) != test.wantAlerts {
+				t.Fatalf("%d alerts, want %d", len(body.Alerts), test.wantAlerts)
+			}
+			if body.Status != test.wantStatus || body.Receiver != "discord" {
+				t.Errorf("status = %q, receiver = %q", body.Status, body.Receiver)
+			}
+			if want := map[string]string{"alertname": test.wantAlertName}; !reflect.DeepEqual(body.GroupLabels, want) {
+				t.Errorf("GroupLabels = %v, want %v", body.GroupLabels, want)
+			}
+
+			fingerprints := map[string]bool{}
+			for _, alert := range body.Alerts {
+				if alert.Status != test.wantStatus || alert.Labels["alertname"] != test.wantAlertName {
+					t.Errorf("Alert %s status = %q, alertname = %q", alert.Fingerprint, alert.Status, alert.Labels["alertname"])
+				}
+				if alert.Labels["level"] != test.options.Severity || body.CommonLabels["level"] != test.options.Severity {
+					t.Errorf("Alert %s level = %q, common level = %q, want %q",
+						alert.Fingerprint, alert.Labels["level"], body.CommonLabels["level"], test.options.Severity)
+				}
+				if resolved := alert.EndsAt != "0001-01-01T00:00:00Z"; resolved != (test.wantStatus == "resolved") {
+					t.Errorf("Alert %s endsAt = %s with status %s", alert.Fingerprint, alert.EndsAt, alert.Status)
+				}
+				fingerprints[alert.Fingerprint] = true
+			}
+			if len(fingerprints) != test.wantAlerts {
+				t.Errorf("%d distinct fingerprints, want %d", len(fingerprints), test.wantAlerts)
+			}
+		})
+	}
+}
+
+func TestSyntheticMessageBodyWithoutSeverity(t *testing.T) {
+	body, err := NewSyntheticMessageBody(SyntheticOptions{}, "severity", "discord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body.Alerts[0].Labels["severity"]; ok {
+		t.Errorf("Labels = %v, want no severity label", body.Alerts[0].Labels)
+	}
+	if _, ok := body.CommonLabels["severity"]; ok {
+		t.Errorf("CommonLabels = %v, want no severity label", body.CommonLabels)
+	}
+}
@@ -9,6 +9,10 @@ type Alert struct {
 	StartsAt     string            `json:"startsAt"`
 	EndsAt       string            `json:"endsAt"`
 	Fingerprint  string            `json:"fingerprint"`
+	// Severity is the effective severity, set by ExtractBodyInfo from the
+	// severity label, or a fallback label, normalized. The labels are left
+	// untouched
+	Severity string `json:"-"`
 }
 
 // MessageBody represents the fields available in Alertmanager's webhook
@@ -22,13 +26,19 @@ type MessageBody struct {
 	ExternalURL       string            `json:"externalURL"`
 	Version           string            `json:"version"`
 	GroupKey          string            `json:"groupKey"`
-	TrucatedAlerts    int32             `json:"truncatedAlerts"`
+	TruncatedAlerts   int32             `json:"truncatedAlerts"`
 }
 
 // MessageBodyInfo is a type with the necessary info to perform the checks
 // and construct other objects, e.g. Discord's WebhookParams
 type MessageBodyInfo struct {
-	FiringCount, ResolvedCount  int
+	FiringCount, ResolvedCount int
+	// TruncatedCount is the number of alerts dropped by Alertmanager's
+	// max_alerts, which aren't in the grouped alerts
+	TruncatedCount int
+	// CollapsedResolvedCount is the number of resolved alerts shown as a
+	// single line instead of embeds, see ApplyResolvedPolicy
+	CollapsedResolvedCount      int
 	CountBySeverity             map[string]int
 	FiringAlertsGroupedByName   AlertsGroupedByLabel
 	ResolvedAlertsGroupedByName AlertsGroupedByLabel
@@ -36,11 +46,17 @@ type MessageBodyInfo struct {
 	CommonLabels                map[string]string
 	CommonAnnotations           map[string]string
 	ExternalURL                 string
+	Receiver                    string
 }
 
 // AlertsGroupedByLabel is just a wrapper for the common case of grouping
-// alerts by label, such as "alertname"
-type AlertsGroupedByLabel map[string]struct {
+// alerts by label, such as "alertname". It's keyed by the composite group key
+type AlertsGroupedByLabel map[string]AlertGroup
+
+// AlertGroup holds the alerts sharing the same values for the groupBy labels
+type AlertGroup struct {
 	Alerts      []Alert
 	GroupLabels map[string]string
+	// KeyLabels are the groupBy labels and their values for this group
+	KeyLabels map[string]string
 }
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/state"
+)
+
+// registerAlertRoutes adds the API listing the firing alerts, of every
+// channel or of the one given as channel query parameter. It's protected by
+// the admin token since the alerts come with their labels and annotations
+func registerAlertRoutes(router *gin.Engine, tracker *state.Tracker, configs config.Config) {
+	router.GET("/api/alerts", requireAdminToken(configs.Admin), func(c *gin.Context) {
+		channelName := c.Query("channel")
+		if _, ok := configs.DiscordChannels[channelName]; channelName != "" && !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown channel " + channelName})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"alerts": tracker.Active(channelName)})
+	})
+}
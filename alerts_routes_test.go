@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/state"
+)
+
+func TestAlertRoutes(t *testing.T) {
+	tracker := state.NewTracker(0, "")
+	tracker.Update("prod", []alertmanager.Alert{
+		{Status: "firing", Fingerprint: "a1", Labels: map[string]string{"alertname": "HighLatency"}},
+	})
+	tracker.Update("staging", []alertmanager.Alert{
+		{Status: "firing", Fingerprint: "b2", Labels: map[string]string{"alertname": "DiskFilling"}},
+	})
+
+	configs := config.Config{
+		Admin: config.AdminConfig{Token: "secret"},
+		DiscordChannels: map[string]config.DiscordChannel{
+			"prod":    {},
+			"staging": {},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	registerAlertRoutes(router, tracker, configs)
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		want          int
+		wantAlerts    int
+	}{
+		{"missing token", "/api/alerts", "", http.StatusUnauthorized, 0},
+		{"wrong token", "/api/alerts", "Bearer other", http.StatusUnauthorized, 0},
+		{"every channel", "/api/alerts", "Bearer secret", http.StatusOK, 2},
+		{"one channel", "/api/alerts?channel=prod", "Bearer secret", http.StatusOK, 1},
+		{"unknown channel", "/api/alerts?channel=dev", "Bearer secret", http.StatusNotFound, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, test.path, nil)
+			if test.authorization != "" {
+				request.Header.Set("Authorization", test.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.want {
+				t.Fatalf("Expected status %d, got %d", test.want, recorder.Code)
+			}
+			if test.want != http.StatusOK {
+				return
+			}
+
+			var response struct {
+				Alerts []state.Alert `json:"alerts"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			if len(response.Alerts) != test.wantAlerts {
+				t.Errorf("Expected %d alerts, got %d", test.wantAlerts, len(response.Alerts))
+			}
+		})
+	}
+}
@@ -2,8 +2,11 @@ package config
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/imdario/mergo"
@@ -13,11 +16,56 @@ import (
 // DiscordChannel contains the necessary Discord DiscordChannel properties
 // for the application
 type DiscordChannel struct {
-	Name                        string   `json:"name" yaml:"name"`
-	WebhookURL                  string   `json:"webhookURL" yaml:"webhookURL"`
-	RolesToMention              []string `json:"rolesToMention" yaml:"rolesToMention"`
-	SeveritiesToMention         []string `json:"severitiesToMention" yaml:"severitiesToMention"`
-	SeveritiesToIgnoreWhenAlone []string `json:"severitiesToIgnoreWhenAlone" yaml:"severitiesToIgnoreWhenAlone"`
+	Name                        string        `json:"name" yaml:"name"`
+	WebhookURL                  WebhookURL    `json:"webhookURL" yaml:"webhookURL"`
+	WebhookURLFile              string        `json:"webhookURLFile" yaml:"webhookURLFile"`
+	RolesToMention              []string      `json:"rolesToMention" yaml:"rolesToMention"`
+	SeveritiesToMention         []string      `json:"severitiesToMention" yaml:"severitiesToMention"`
+	SeveritiesToIgnoreWhenAlone []string      `json:"severitiesToIgnoreWhenAlone" yaml:"severitiesToIgnoreWhenAlone"`
+	GroupBy                     []string      `json:"groupBy" yaml:"groupBy"`
+	Summary                     SummaryConfig `json:"summary" yaml:"summary"`
+	Windows                     []TimeWindow  `json:"windows" yaml:"windows"`
+	// Name of the rotation whose on-call user is mentioned instead of the
+	// rolesToMention
+	Rotation string         `json:"rotation" yaml:"rotation"`
+	Resolved ResolvedPolicy `json:"resolved" yaml:"resolved"`
+	Filters  []AlertFilter  `json:"filters" yaml:"filters"`
+}
+
+// AlertFilter keeps or drops the alerts matching all of its matchers
+type AlertFilter struct {
+	// "include" keeps only the alerts matching one of the include filters,
+	// "exclude" drops the matching alerts, and "dropWhenAlone" drops the
+	// message when all of its alerts match, as severitiesToIgnoreWhenAlone
+	Action string `json:"action" yaml:"action"`
+	// Prometheus-style matchers, e.g. namespace=~"staging|dev". Annotations
+	// are matched with the "annotations." prefix, e.g. annotations.runbook!=""
+	Matchers []string `json:"matchers" yaml:"matchers"`
+}
+
+// ResolvedPolicy tells which resolved alerts are sent to the channel
+type ResolvedPolicy struct {
+	// "all", "none", "severities", or "collapsed", which replaces the
+	// resolved embeds with a single "N alerts resolved" line. Defaults to
+	// "all"
+	Send string `json:"send" yaml:"send"`
+	// Severities whose resolved alerts are sent with "severities"
+	Severities []string `json:"severities" yaml:"severities"`
+}
+
+// SummaryConfig defines the scheduled summary report of a channel, listing
+// its firing alerts and the alerts fired and resolved over the period
+type SummaryConfig struct {
+	// Cron expression, e.g. "0 9 * * 1-5". No summary is sent when empty
+	Schedule string `json:"schedule" yaml:"schedule"`
+	// IANA time zone of the schedule, e.g. "Europe/Berlin". Defaults to UTC
+	Timezone string `json:"timezone" yaml:"timezone"`
+	// Period counted for the fired and resolved alerts. Defaults to "24h"
+	Period string `json:"period" yaml:"period"`
+	// Number of longest firing alerts listed. Defaults to 5
+	LongestFiring int `json:"longestFiring" yaml:"longestFiring"`
+	// Send the summary even when no alert is firing, fired or resolved
+	SendWhenEmpty bool `json:"sendWhenEmpty" yaml:"sendWhenEmpty"`
 }
 
 // StatusAppearance defines the Embed's color and Emoji to be used in the title
@@ -31,11 +79,17 @@ type SeverityAppearance struct {
 	Color    int    `json:"color" yaml:"color"`
 	Emoji    string `json:"emoji" yaml:"emoji"`
 	Priority int    `json:"priority" yaml:"priority"`
+	// Other values of the label meaning this severity, e.g. "crit" for
+	// "critical". Values and aliases are matched case-insensitively
+	Aliases []string `json:"aliases" yaml:"aliases"`
 }
 
 type SeverityDefinition struct {
-	Label  string                        `json:"label" yaml:"label"`
-	Values map[string]SeverityAppearance `json:"values" yaml:"values"`
+	Label string `json:"label" yaml:"label"`
+	// Labels used, in order, when the alert doesn't have the label, e.g.
+	// priority or level
+	FallbackLabels []string                      `json:"fallbackLabels" yaml:"fallbackLabels"`
+	Values         map[string]SeverityAppearance `json:"values" yaml:"values"`
 }
 
 // DashboardLinkConfig defines configuration for dashboard links
@@ -43,16 +97,40 @@ type DashboardLinkConfig struct {
 	Enabled bool   `json:"enabled" yaml:"enabled"`
 	Label   string `json:"label" yaml:"label"`
 	Text    string `json:"text" yaml:"text"`
-	// Position for dashboard link: "content", "embed_top", or "embed_bottom"
+	// Position for dashboard link: "content", "embed_top", "embed_bottom" or "field"
 	Position string `json:"position" yaml:"position"`
+	// Link each alert to its own dashboard when the alerts of a group don't
+	// share the same one
+	PerAlert bool `json:"perAlert" yaml:"perAlert"`
 }
 
 // GeneratorLinkConfig defines configuration for generator links
 type GeneratorLinkConfig struct {
 	Enabled bool   `json:"enabled" yaml:"enabled"`
 	Text    string `json:"text" yaml:"text"`
-	// Position for generator link: "content", "embed_top", or "embed_bottom"
+	// Position for generator link: "content", "embed_top", "embed_bottom" or "field"
 	Position string `json:"position" yaml:"position"`
+	// Link each alert to its own query when the alerts of a group don't share
+	// the same one
+	PerAlert bool `json:"perAlert" yaml:"perAlert"`
+}
+
+// LinkConfig defines a link added to the messages
+type LinkConfig struct {
+	Text string `json:"text" yaml:"text"`
+	// URL is a Go template evaluated for each alert of a group, with the
+	// alert's .Labels, .Annotations and .GeneratorURL, and the notification's
+	// .GroupLabels, .CommonLabels, .CommonAnnotations, .ExternalURL and
+	// .Receiver. Alerts for which it evaluates to an empty string get no link
+	URL string `json:"url" yaml:"url"`
+	// Condition is a label or annotation name, the link is only added for
+	// alerts that have it
+	Condition string `json:"condition" yaml:"condition"`
+	// Position for the link: "content", "embed_top", "embed_bottom" or "field"
+	Position string `json:"position" yaml:"position"`
+	// Link each alert separately when the alerts of a group don't share the
+	// same URL
+	PerAlert bool `json:"perAlert" yaml:"perAlert"`
 }
 
 // TimeDisplayConfig defines configuration for time display
@@ -64,10 +142,189 @@ type TimeDisplayConfig struct {
 	HiddenForSeverities []string `json:"hiddenForSeverities" yaml:"hiddenForSeverities"`
 }
 
+// GrafanaImageConfig defines configuration for attaching Grafana panel
+// renders to the embeds
+type GrafanaImageConfig struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	URL      string `json:"url" yaml:"url"`
+	APIToken Secret `json:"apiToken" yaml:"apiToken"`
+	// File to read the API token from, instead of APIToken
+	APITokenFile string `json:"apiTokenFile" yaml:"apiTokenFile"`
+	Width        int    `json:"width" yaml:"width"`
+	Height       int    `json:"height" yaml:"height"`
+	// How much time before and after the alert's StartsAt should be rendered.
+	// An empty TimeRangeAfter renders up to the current time.
+	TimeRangeBefore string `json:"timeRangeBefore" yaml:"timeRangeBefore"`
+	TimeRangeAfter  string `json:"timeRangeAfter" yaml:"timeRangeAfter"`
+	// Timeout bounds the renders of a message, which are made in parallel
+	Timeout string `json:"timeout" yaml:"timeout"`
+	// Annotations carrying the dashboard UID and the panel ID of the alert
+	DashboardUIDAnnotation string `json:"dashboardUIDAnnotation" yaml:"dashboardUIDAnnotation"`
+	PanelIDAnnotation      string `json:"panelIDAnnotation" yaml:"panelIDAnnotation"`
+}
+
+// AlertDetailsConfig defines configuration for attaching the full list of
+// alerts as a file to the message
+type AlertDetailsConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Format of the attached file: "csv", "json" or "markdown"
+	Format string `json:"format" yaml:"format"`
+	// When to attach the file: "overflow" (only when the embeds don't fit in
+	// Discord's limits) or "always"
+	When string `json:"when" yaml:"when"`
+}
+
+// SortConfig defines the order of the embeds and of the alerts inside each
+// embed. Keys are applied in order, and prefixing a key with "-" reverses it
+type SortConfig struct {
+	// Keys for embeds: "priority" (highest first), "count" (most alerts
+	// first), "startsAt" (earliest first) and "title" (alphabetical)
+	Groups []string `json:"groups" yaml:"groups"`
+	// Keys for alerts: "startsAt" (earliest first), "severity" (highest
+	// priority first) and "label:<name>" (alphabetical by label value)
+	Alerts []string `json:"alerts" yaml:"alerts"`
+	// Sort firing and resolved embeds together instead of firing ones first
+	InterleaveStatus bool `json:"interleaveStatus" yaml:"interleaveStatus"`
+}
+
+// WebhookCheckConfig defines the periodic verification of the channels'
+// webhooks, whose results are exposed in the status endpoint
+type WebhookCheckConfig struct {
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	Interval string `json:"interval" yaml:"interval"`
+	Timeout  string `json:"timeout" yaml:"timeout"`
+	// Report the application as not ready while any webhook is invalid
+	AffectsReadiness bool `json:"affectsReadiness" yaml:"affectsReadiness"`
+}
+
+// LoggingConfig defines the format and verbosity of the logs
+type LoggingConfig struct {
+	// debug, info, warn or error
+	Level string `json:"level" yaml:"level"`
+	// logfmt or json
+	Format string `json:"format" yaml:"format"`
+}
+
+// TracingConfig defines the export of OpenTelemetry traces
+type TracingConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// "otlp" exports to an OTLP/HTTP collector, "memory" keeps the spans in
+	// memory, for tests
+	Exporter string `json:"exporter" yaml:"exporter"`
+	// Endpoint of the collector, e.g. "otel-collector:4318". When empty, the
+	// OTEL_EXPORTER_OTLP_* environment variables are used
+	Endpoint    string  `json:"endpoint" yaml:"endpoint"`
+	Insecure    bool    `json:"insecure" yaml:"insecure"`
+	ServiceName string  `json:"serviceName" yaml:"serviceName"`
+	SampleRatio float64 `json:"sampleRatio" yaml:"sampleRatio"`
+}
+
+// AdminConfig protects the admin API, which is disabled unless a token is set
+type AdminConfig struct {
+	// Bearer token expected in the Authorization header
+	Token Secret `json:"token" yaml:"token"`
+	// File to read the token from, instead of Token
+	TokenFile string `json:"tokenFile" yaml:"tokenFile"`
+}
+
+// HistoryConfig defines the store of processed notifications, queried through
+// the history API
+type HistoryConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// "memory" or "file"
+	Storage string `json:"storage" yaml:"storage"`
+	// Path of the JSON lines file of the "file" storage
+	Path       string `json:"path" yaml:"path"`
+	MaxEntries int    `json:"maxEntries" yaml:"maxEntries"`
+	Retention  string `json:"retention" yaml:"retention"`
+}
+
+// DashboardConfig defines the built-in web dashboard
+type DashboardConfig struct {
+	Enabled                 bool `json:"enabled" yaml:"enabled"`
+	NotificationsPerChannel int  `json:"notificationsPerChannel" yaml:"notificationsPerChannel"`
+}
+
+// AlertStateConfig defines the tracking of the firing alerts of each channel
+type AlertStateConfig struct {
+	// Firing alerts not received again for this long are dropped, in case
+	// Alertmanager stops sending them
+	ExpireAfter string `json:"expireAfter" yaml:"expireAfter"`
+	// File the state is saved to, so restarts don't reset it. Not saved when
+	// empty
+	Path string `json:"path" yaml:"path"`
+}
+
+// TimeWindow is a period during which the channel's messages are suppressed
+// or its mentions stripped or downgraded, such as quiet hours or a planned
+// maintenance. It's active when the time is within all the conditions set
+type TimeWindow struct {
+	Name string `json:"name" yaml:"name"`
+	// Days of the week, e.g. "monday", "sat" or "mon-fri". Every day when empty
+	Weekdays []string `json:"weekdays" yaml:"weekdays"`
+	// Ranges of the day, e.g. "09:00-18:00". Ranges end by "24:00" at the
+	// latest, so overnight windows are split in two. The whole day when empty
+	Times []string `json:"times" yaml:"times"`
+	// IANA time zone of the weekdays, times, start and end, e.g.
+	// "Europe/Berlin". Defaults to UTC
+	Timezone string `json:"timezone" yaml:"timezone"`
+	// One-off range, as RFC 3339 or "2006-01-02 15:04" in the time zone.
+	// Either can be empty to leave the range open
+	Start string `json:"start" yaml:"start"`
+	End   string `json:"end" yaml:"end"`
+	// "suppress", "stripMentions" or "downgradeMentions"
+	Action string `json:"action" yaml:"action"`
+	// Roles mentioned instead of the channel's ones by "downgradeMentions"
+	RolesToMention []string `json:"rolesToMention" yaml:"rolesToMention"`
+	// The window only applies to messages whose alerts all have one of these
+	// severities. Any message when empty
+	Severities []string `json:"severities" yaml:"severities"`
+}
+
+// Rotation is an on-call schedule: users take turns of the given length,
+// handing off at the handoff time. Overrides, then the events of the iCal
+// file, take precedence over the turns
+type Rotation struct {
+	// Discord user IDs, in the order of their turns
+	Users []string `json:"users" yaml:"users"`
+	// Length of a turn, e.g. "168h" for a week. Defaults to "168h"
+	Length string `json:"length" yaml:"length"`
+	// Date of the first user's first turn, e.g. "2026-01-05"
+	Start string `json:"start" yaml:"start"`
+	// Time of the day turns start, e.g. "09:00". Defaults to "00:00"
+	Handoff string `json:"handoff" yaml:"handoff"`
+	// IANA time zone of the start, handoff and overrides, e.g.
+	// "Europe/Berlin". Defaults to UTC
+	Timezone  string             `json:"timezone" yaml:"timezone"`
+	Overrides []RotationOverride `json:"overrides" yaml:"overrides"`
+	// iCal (.ics) file of the schedule, read on every message. The summary of
+	// the event in progress is the on-call user, as a Discord user ID or a key
+	// of icalUsers
+	ICalFile  string            `json:"icalFile" yaml:"icalFile"`
+	ICalUsers map[string]string `json:"icalUsers" yaml:"icalUsers"`
+}
+
+// RotationOverride puts a user on call from start to end, as RFC 3339 or
+// "2006-01-02 15:04" in the rotation's time zone
+type RotationOverride struct {
+	User  string `json:"user" yaml:"user"`
+	Start string `json:"start" yaml:"start"`
+	End   string `json:"end" yaml:"end"`
+}
+
 // Config defines the (.yaml|.json) config structured to be used by the app
 type Config struct {
 	AvatarURL                   string                      `json:"avatarURL" yaml:"avatarURL"`
 	ListenAddress               string                      `json:"listenAddress,omitempty" yaml:"listenAddress,omitempty"`
+	DisableConfigLog            bool                        `json:"disableConfigLog" yaml:"disableConfigLog"`
+	Logging                     LoggingConfig               `json:"logging" yaml:"logging"`
+	Tracing                     TracingConfig               `json:"tracing" yaml:"tracing"`
+	Admin                       AdminConfig                 `json:"admin" yaml:"admin"`
+	History                     HistoryConfig               `json:"history" yaml:"history"`
+	Dashboard                   DashboardConfig             `json:"dashboard" yaml:"dashboard"`
+	AlertState                  AlertStateConfig            `json:"alertState" yaml:"alertState"`
+	DrainDelay                  string                      `json:"drainDelay" yaml:"drainDelay"`
+	ShutdownTimeout             string                      `json:"shutdownTimeout" yaml:"shutdownTimeout"`
 	Username                    string                      `json:"username" yaml:"username"`
 	MessageType                 string                      `json:"messageType" yaml:"messageType"`
 	Status                      map[string]StatusAppearance `json:"status" yaml:"status"`
@@ -75,19 +332,29 @@ type Config struct {
 	RolesToMention              []string                    `json:"rolesToMention" yaml:"rolesToMention"`
 	SeveritiesToMention         []string                    `json:"severitiesToMention" yaml:"severitiesToMention"`
 	SeveritiesToIgnoreWhenAlone []string                    `json:"severitiesToIgnoreWhenAlone" yaml:"severitiesToIgnoreWhenAlone"`
+	GroupBy                     []string                    `json:"groupBy" yaml:"groupBy"`
 	Severity                    SeverityDefinition          `json:"severity" yaml:"severity"`
 	DashboardLink               DashboardLinkConfig         `json:"dashboardLink" yaml:"dashboardLink"`
 	GeneratorLink               GeneratorLinkConfig         `json:"generatorLink" yaml:"generatorLink"`
+	Links                       []LinkConfig                `json:"links" yaml:"links"`
 	TimeDisplay                 TimeDisplayConfig           `json:"timeDisplay" yaml:"timeDisplay"`
+	GrafanaImage                GrafanaImageConfig          `json:"grafanaImage" yaml:"grafanaImage"`
+	AlertDetails                AlertDetailsConfig          `json:"alertDetails" yaml:"alertDetails"`
+	Sort                        SortConfig                  `json:"sort" yaml:"sort"`
+	WebhookCheck                WebhookCheckConfig          `json:"webhookCheck" yaml:"webhookCheck"`
+	Rotations                   map[string]Rotation         `json:"rotations" yaml:"rotations"`
 	DiscordChannels             map[string]DiscordChannel   `json:"channels" yaml:"channels"`
 }
 
 var defaultConfig = Config{
 	ListenAddress:        ":8080",
+	DrainDelay:           "5s",
+	ShutdownTimeout:      "30s",
 	MessageType:          "status",
 	AvatarURL:            "https://raw.githubusercontent.com/kolesaev/alertmanager-discord/master/assets/images/prometheus-logo.png",
 	Username:             "alertmanager",
 	FiringCountToMention: -1,
+	GroupBy:              []string{"alertname"},
 	Status: map[string]StatusAppearance{
 		"firing": {
 			Emoji: ":rotating_light:",
@@ -105,10 +372,6 @@ var defaultConfig = Config{
 				Color: 9807270, // EmbedColorGrey
 				Emoji: ":grey_question:",
 			},
-			"information": {
-				Color: 3447003, // EmbedColorBlue
-				Emoji: ":information_source:",
-			},
 			"info": {
 				Color: 3447003, // EmbedColorBlue
 				Emoji: ":information_source:",
@@ -118,11 +381,6 @@ var defaultConfig = Config{
 				Emoji:    ":warning:",
 				Priority: 1,
 			},
-			"warn": {
-				Color:    15844367, // EmbedColorGold
-				Emoji:    ":warning:",
-				Priority: 1,
-			},
 			"critical": {
 				Color:    11027200, // EmbedColorDarkOrange
 				Emoji:    ":rotating_light:",
@@ -153,60 +411,227 @@ var defaultConfig = Config{
 		DurationText:        "Duration:",
 		HiddenForSeverities: []string{},
 	},
+	GrafanaImage: GrafanaImageConfig{
+		Enabled:                false,
+		Width:                  1000,
+		Height:                 500,
+		TimeRangeBefore:        "1h",
+		Timeout:                "5s",
+		DashboardUIDAnnotation: "__dashboardUid__",
+		PanelIDAnnotation:      "__panelId__",
+	},
+	AlertDetails: AlertDetailsConfig{
+		Enabled: false,
+		Format:  "csv",
+		When:    "overflow",
+	},
+	Sort: SortConfig{
+		Groups:           []string{"priority"},
+		Alerts:           []string{},
+		InterleaveStatus: false,
+	},
+	WebhookCheck: WebhookCheckConfig{
+		Enabled:          false,
+		Interval:         "5m",
+		Timeout:          "10s",
+		AffectsReadiness: false,
+	},
+	Logging: LoggingConfig{
+		Level:  "info",
+		Format: "logfmt",
+	},
+	Tracing: TracingConfig{
+		Enabled:     false,
+		Exporter:    "otlp",
+		ServiceName: "alertmanager-discord",
+		SampleRatio: 1,
+	},
+	History: HistoryConfig{
+		Enabled:    false,
+		Storage:    "memory",
+		Path:       "history.jsonl",
+		MaxEntries: 1000,
+		Retention:  "168h",
+	},
+	Dashboard: DashboardConfig{
+		Enabled:                 false,
+		NotificationsPerChannel: 10,
+	},
+	AlertState: AlertStateConfig{
+		ExpireAfter: "24h",
+	},
 }
 
-// LoadUserConfig provides a Config struct to be used throughout the application
-func LoadUserConfig() *Config {
-	configFilePath := getEnv("CONFIG_PATH", "./config.yaml")
+// LoadUserConfig provides a Config struct to be used throughout the application.
+// Settings are taken, in order of precedence, from the command line flags in
+// args, AMD_* environment variables, the config file and defaultConfig. The
+// config path (--config or CONFIG_PATH) can be a file, a directory or a glob
+// of config fragments, and may be omitted to configure everything through the
+// environment
+func LoadUserConfig(args []string) *Config {
+	flags := newConfigFlags("alertmanager-discord")
+	if err := flags.flagSet.Parse(args); err != nil {
+		exitOnError(err)
+	}
+
+	configPath, explicitPath := lookupConfigPath(flags)
 
-	userConfig := loadConfigurationFile(configFilePath)
+	var userConfig Config
+	if _, err := os.Stat(configPath); err != nil && !explicitPath {
+		slog.Info("No config file found, using defaults and environment variables", "path", configPath)
+	} else {
+		userConfig, err = loadConfiguration(configPath)
+		if err != nil {
+			exitOnError(err)
+		}
+	}
 
 	config := defaultConfig
 
 	err := mergo.Merge(&config, userConfig, mergo.WithOverride)
 	if err != nil {
-		log.Fatalln(err)
+		exitOnError(err)
 	}
 
-	yamlConfig, err := yaml.Marshal(config)
-	if err != nil {
-		log.Fatal(err)
+	if err := applyEnv(&config, os.Environ()); err != nil {
+		exitOnError(err)
+	}
+
+	if err := flags.apply(&config); err != nil {
+		exitOnError(err)
+	}
+
+	addDefaultSeverityAliases(&config)
+	normalizeSeverityLists(&config)
+
+	if err := validateSecretFiles(config); err != nil {
+		exitOnError(err)
+	}
+
+	if err := validateResolvedPolicies(config); err != nil {
+		exitOnError(err)
+	}
+
+	if err := validateSortKeys(config.Sort); err != nil {
+		exitOnError(err)
+	}
+
+	if err := validateLinks(config); err != nil {
+		exitOnError(err)
 	}
-	log.Printf("Using the following config:\n\n=======\n\n%s\n\n========\n\n", string(yamlConfig))
 
 	return &config
 }
 
-func loadConfigurationFile(file string) Config {
-	var config Config
+// LogConfig logs the merged config, unless disableConfigLog is set. It's
+// called once the logger is set up from the config
+func LogConfig(config Config) {
+	if config.DisableConfigLog {
+		return
+	}
 
-	configFile, err := os.Open(file)
+	// Secrets are masked by their MarshalYAML
+	yamlConfig, err := yaml.Marshal(config)
 	if err != nil {
-		log.Fatalln(err)
+		exitOnError(err)
 	}
+	slog.Info("Using the following config", "config", string(yamlConfig))
+}
 
-	defer configFile.Close()
+// validateResolvedPolicies checks the resolved policy of every channel
+func validateResolvedPolicies(config Config) error {
+	for channelName, discordChannel := range config.DiscordChannels {
+		switch discordChannel.Resolved.Send {
+		case "", "all", "none", "collapsed":
+		case "severities":
+			if len(discordChannel.Resolved.Severities) == 0 {
+				return fmt.Errorf(
+					"config.validateResolvedPolicies: Channel %s: resolved.severities is needed with \"severities\"",
+					channelName)
+			}
+		default:
+			return fmt.Errorf("config.validateResolvedPolicies: Channel %s: Invalid resolved.send %q",
+				channelName, discordChannel.Resolved.Send)
+		}
+	}
+	return nil
+}
 
-	if strings.HasSuffix(file, ".json") {
-		jsonParser := json.NewDecoder(configFile)
-		err := jsonParser.Decode(&config)
-		if err != nil {
-			log.Fatal(err)
+// validateSortKeys checks the keys of the embeds and alerts order, which
+// would otherwise be ignored
+func validateSortKeys(sortConfig SortConfig) error {
+	for _, sortKey := range sortConfig.Groups {
+		switch strings.TrimPrefix(sortKey, "-") {
+		case "priority", "count", "startsAt", "title":
+		default:
+			return fmt.Errorf("config.validateSortKeys: Invalid sort.groups key %q", sortKey)
 		}
-	} else if strings.HasSuffix(file, ".yaml") || strings.HasSuffix(file, ".yml") {
-		yamlParser := yaml.NewDecoder(configFile)
-		err := yamlParser.Decode(&config)
-		if err != nil {
-			log.Fatal(err)
+	}
+
+	for _, sortKey := range sortConfig.Alerts {
+		key := strings.TrimPrefix(sortKey, "-")
+		switch {
+		case key == "startsAt", key == "severity":
+		case strings.HasPrefix(key, "label:") && key != "label:":
+		default:
+			return fmt.Errorf("config.validateSortKeys: Invalid sort.alerts key %q", sortKey)
 		}
 	}
 
-	return config
+	return nil
 }
 
-func getEnv(key, fallback string) string {
-	if value, ok := os.LookupEnv(key); ok {
-		return value
+func exitOnError(err error) {
+	slog.Error("Error loading the config", "error", err)
+	os.Exit(1)
+}
+
+// loadConfigurationFile parses a .json, .yaml or .yml config file, also
+// returning its top level keys and channel keys to detect conflicts between
+// fragments
+func loadConfigurationFile(file string) (configFragment, error) {
+	fragment := configFragment{file: file}
+
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fragment, fmt.Errorf("config.loadConfigurationFile: \n%+v", err)
+	}
+
+	var keys map[string]interface{}
+
+	switch filepath.Ext(file) {
+	case ".json":
+		contents, err = expandEnvJSON(contents)
+		if err == nil {
+			err = json.Unmarshal(contents, &fragment.config)
+		}
+		if err == nil {
+			err = json.Unmarshal(contents, &keys)
+		}
+	case ".yaml", ".yml":
+		contents, err = expandEnvYAML(contents)
+		if err == nil {
+			err = yaml.Unmarshal(contents, &fragment.config)
+		}
+		if err == nil {
+			err = yaml.Unmarshal(contents, &keys)
+		}
+	default:
+		return fragment, fmt.Errorf(
+			"config.loadConfigurationFile: %s: Unknown extension, expected .json, .yaml or .yml", file)
+	}
+	if err != nil {
+		return fragment, fmt.Errorf("config.loadConfigurationFile: %s: \n%+v", file, err)
+	}
+
+	for key := range keys {
+		fragment.keys = append(fragment.keys, key)
 	}
-	return fallback
+	if channels, ok := keys["channels"].(map[string]interface{}); ok {
+		for channelKey := range channels {
+			fragment.channelKeys = append(fragment.channelKeys, channelKey)
+		}
+	}
+
+	return fragment, nil
 }
@@ -0,0 +1,30 @@
+package config
+
+import "testing"
+
+func TestValidateSortKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		sortConfig SortConfig
+		wantErr    bool
+	}{
+		{"defaults", defaultConfig.Sort, false},
+		{"every key", SortConfig{
+			Groups: []string{"priority", "-count", "startsAt", "-title"},
+			Alerts: []string{"-startsAt", "severity", "label:instance", "-label:job"},
+		}, false},
+		{"unknown group key", SortConfig{Groups: []string{"priority", "severity"}}, true},
+		{"unknown alert key", SortConfig{Alerts: []string{"count"}}, true},
+		{"label without name", SortConfig{Alerts: []string{"label:"}}, true},
+		{"misspelled key", SortConfig{Groups: []string{"startsat"}}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateSortKeys(test.sortConfig)
+			if (err != nil) != test.wantErr {
+				t.Errorf("validateSortKeys() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
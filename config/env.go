@@ -0,0 +1,335 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix is the prefix of the environment variables setting config fields,
+// e.g. AMD_MESSAGE_TYPE for messageType
+const envPrefix = "AMD_"
+
+// envMapNames overrides the environment name of map fields, which is
+// otherwise the upper snake case of their yaml key
+var envMapNames = map[string]string{
+	"channels": "CHANNEL",
+}
+
+// configLeaf is a config field set as a whole from a single environment
+// variable or flag, such as a string, a number or a list
+type configLeaf struct {
+	// Path of yaml keys, e.g. ["timeDisplay", "enabled"]
+	path  []string
+	index []int
+}
+
+func (l configLeaf) envName() string {
+	parts := make([]string, len(l.path))
+	for i, key := range l.path {
+		parts[i] = toUpperSnakeCase(key)
+	}
+	return strings.Join(parts, "_")
+}
+
+func (l configLeaf) flagName() string {
+	parts := make([]string, len(l.path))
+	for i, key := range l.path {
+		parts[i] = strings.ToLower(strings.ReplaceAll(toUpperSnakeCase(key), "_", "-"))
+	}
+	return strings.Join(parts, ".")
+}
+
+// configMap is a map of structs in the config, e.g. channels, whose entries
+// are set from environment variables like AMD_CHANNEL_<KEY>_WEBHOOK_URL
+type configMap struct {
+	envName string
+	index   []int
+	leaves  []configLeaf
+}
+
+// listConfigFields walks a struct type, returning its leaves and maps
+func listConfigFields(structType reflect.Type, path []string, index []int) ([]configLeaf, []configMap) {
+	var leaves []configLeaf
+	var maps []configMap
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+
+		fieldPath := append(append([]string{}, path...), key)
+		fieldIndex := append(append([]int{}, index...), i)
+
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			structLeaves, structMaps := listConfigFields(field.Type, fieldPath, fieldIndex)
+			leaves = append(leaves, structLeaves...)
+			maps = append(maps, structMaps...)
+		case field.Type.Kind() == reflect.Map && field.Type.Elem().Kind() == reflect.Struct:
+			envName := configLeaf{path: fieldPath}.envName()
+			if override, ok := envMapNames[strings.Join(fieldPath, ".")]; ok {
+				envName = override
+			}
+			entryLeaves, _ := listConfigFields(field.Type.Elem(), nil, nil)
+			maps = append(maps, configMap{envName: envName, index: fieldIndex, leaves: entryLeaves})
+		default:
+			leaves = append(leaves, configLeaf{path: fieldPath, index: fieldIndex})
+		}
+	}
+
+	return leaves, maps
+}
+
+// applyEnv sets the config fields defined by AMD_* environment variables.
+// Lists of strings are comma separated, and other lists, maps and structs
+// are parsed as YAML
+func applyEnv(config *Config, environ []string) error {
+	env := map[string]string{}
+	for _, variable := range environ {
+		parts := strings.SplitN(variable, "=", 2)
+		if len(parts) == 2 && strings.HasPrefix(parts[0], envPrefix) {
+			env[strings.TrimPrefix(parts[0], envPrefix)] = parts[1]
+		}
+	}
+
+	leaves, maps := listConfigFields(reflect.TypeOf(*config), nil, nil)
+	configValue := reflect.ValueOf(config).Elem()
+
+	for _, leaf := range leaves {
+		value, ok := env[leaf.envName()]
+		if !ok {
+			continue
+		}
+		if err := setConfigField(configValue.FieldByIndex(leaf.index), value); err != nil {
+			return fmt.Errorf("config.applyEnv: %s%s: \n%+v", envPrefix, leaf.envName(), err)
+		}
+	}
+
+	for _, configMap := range maps {
+		if err := applyEnvToMap(configValue.FieldByIndex(configMap.index), configMap, env); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyEnvToMap sets the map entries' fields from variables named
+// <MAP>_<KEY>_<FIELD>. The key is lower cased and underscores are replaced by
+// hyphens, so AMD_CHANNEL_TEAM_GO_WEBHOOK_URL sets channels["team-go"]
+func applyEnvToMap(mapValue reflect.Value, configMap configMap, env map[string]string) error {
+	// Longest field names first, so WEBHOOK_URL_FILE isn't taken for WEBHOOK_URL
+	leaves := append([]configLeaf{}, configMap.leaves...)
+	sort.Slice(leaves, func(i, j int) bool {
+		return len(leaves[i].envName()) > len(leaves[j].envName())
+	})
+
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !strings.HasPrefix(name, configMap.envName+"_") {
+			continue
+		}
+		rest := strings.TrimPrefix(name, configMap.envName+"_")
+
+		for _, leaf := range leaves {
+			if !strings.HasSuffix(rest, "_"+leaf.envName()) {
+				continue
+			}
+
+			key := strings.TrimSuffix(rest, "_"+leaf.envName())
+			key = strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+
+			if mapValue.IsNil() {
+				mapValue.Set(reflect.MakeMap(mapValue.Type()))
+			}
+
+			entry := reflect.New(mapValue.Type().Elem()).Elem()
+			if existing := mapValue.MapIndex(reflect.ValueOf(key)); existing.IsValid() {
+				entry.Set(existing)
+			}
+
+			if err := setConfigField(entry.FieldByIndex(leaf.index), env[name]); err != nil {
+				return fmt.Errorf("config.applyEnvToMap: %s%s: \n%+v", envPrefix, name, err)
+			}
+
+			mapValue.SetMapIndex(reflect.ValueOf(key), entry)
+			break
+		}
+	}
+
+	return nil
+}
+
+func setConfigField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	case reflect.Int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(parsed))
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String {
+			values := []string{}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					values = append(values, item)
+				}
+			}
+			field.Set(reflect.ValueOf(values).Convert(field.Type()))
+			return nil
+		}
+		fallthrough
+	default:
+		parsed := reflect.New(field.Type())
+		if err := yaml.Unmarshal([]byte(value), parsed.Interface()); err != nil {
+			return err
+		}
+		field.Set(parsed.Elem())
+	}
+
+	return nil
+}
+
+// configFlags holds the command line flags, one for each config leaf plus
+// --config for the config path
+type configFlags struct {
+	flagSet    *flag.FlagSet
+	configPath string
+	leaves     []configLeaf
+	values     map[string]string
+}
+
+// flagValue records the raw value of a flag, applied once the config file
+// and environment variables are loaded
+type flagValue struct {
+	name   string
+	isBool bool
+	values map[string]string
+}
+
+func (f flagValue) String() string {
+	return f.values[f.name]
+}
+
+func (f flagValue) Set(value string) error {
+	f.values[f.name] = value
+	return nil
+}
+
+// IsBoolFlag allows boolean fields to be set with just --flag
+func (f flagValue) IsBoolFlag() bool {
+	return f.isBool
+}
+
+func newConfigFlags(name string) *configFlags {
+	flags := &configFlags{
+		flagSet: flag.NewFlagSet(name, flag.ExitOnError),
+		values:  map[string]string{},
+	}
+
+	flags.flagSet.StringVar(&flags.configPath, "config", "",
+		"Path of the config file, directory or glob. Overrides CONFIG_PATH")
+
+	configType := reflect.TypeOf(Config{})
+	flags.leaves, _ = listConfigFields(configType, nil, nil)
+	for _, leaf := range flags.leaves {
+		isBool := configType.FieldByIndex(leaf.index).Type.Kind() == reflect.Bool
+		flags.flagSet.Var(
+			flagValue{name: leaf.flagName(), isBool: isBool, values: flags.values},
+			leaf.flagName(),
+			fmt.Sprintf("Sets %s. Overrides %s%s", strings.Join(leaf.path, "."), envPrefix, leaf.envName()))
+	}
+
+	return flags
+}
+
+// ForwardFlags defines --config and the config flags on flagSet, for commands
+// with flags of their own. Once flagSet is parsed, the returned function lists
+// the config flags given, to be passed to LoadUserConfig
+func ForwardFlags(flagSet *flag.FlagSet) func() []string {
+	flags := newConfigFlags(flagSet.Name())
+	flags.flagSet.VisitAll(func(configFlag *flag.Flag) {
+		flagSet.Var(configFlag.Value, configFlag.Name, configFlag.Usage)
+	})
+
+	return func() []string {
+		args := []string{}
+		flagSet.Visit(func(givenFlag *flag.Flag) {
+			if flags.flagSet.Lookup(givenFlag.Name) != nil {
+				args = append(args, "--"+givenFlag.Name+"="+givenFlag.Value.String())
+			}
+		})
+		return args
+	}
+}
+
+// apply sets the config fields of the flags given in the command line
+func (f *configFlags) apply(config *Config) error {
+	configValue := reflect.ValueOf(config).Elem()
+
+	for _, leaf := range f.leaves {
+		value, ok := f.values[leaf.flagName()]
+		if !ok {
+			continue
+		}
+		if err := setConfigField(configValue.FieldByIndex(leaf.index), value); err != nil {
+			return fmt.Errorf("config.apply: --%s: \n%+v", leaf.flagName(), err)
+		}
+	}
+
+	return nil
+}
+
+// toUpperSnakeCase converts a yaml key to an environment name, e.g.
+// webhookURLFile to WEBHOOK_URL_FILE
+func toUpperSnakeCase(key string) string {
+	runes := []rune(key)
+	var builder strings.Builder
+
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previousLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if previousLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				builder.WriteRune('_')
+			}
+		}
+		builder.WriteRune(unicode.ToUpper(r))
+	}
+
+	return builder.String()
+}
+
+func lookupConfigPath(flags *configFlags) (path string, explicit bool) {
+	if flags.configPath != "" {
+		return flags.configPath, true
+	}
+	if path, ok := os.LookupEnv("CONFIG_PATH"); ok {
+		return path, true
+	}
+	return "./config.yaml", false
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestToUpperSnakeCase(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"username", "USERNAME"},
+		{"timeDisplay", "TIME_DISPLAY"},
+		{"webhookURL", "WEBHOOK_URL"},
+		{"webhookURLFile", "WEBHOOK_URL_FILE"},
+		{"dashboardUIDAnnotation", "DASHBOARD_UID_ANNOTATION"},
+		{"maxEntries", "MAX_ENTRIES"},
+		{"v2Enabled", "V2_ENABLED"},
+	}
+
+	for _, test := range tests {
+		if got := toUpperSnakeCase(test.key); got != test.want {
+			t.Errorf("toUpperSnakeCase(%q) = %q, want %q", test.key, got, test.want)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		wantErr bool
+		check   func(t *testing.T, config Config)
+	}{
+		{
+			name:    "string",
+			environ: []string{"AMD_USERNAME=Alertmanager"},
+			check: func(t *testing.T, config Config) {
+				if config.Username != "Alertmanager" {
+					t.Errorf("username = %q", config.Username)
+				}
+			},
+		},
+		{
+			name:    "nested bool and list",
+			environ: []string{"AMD_TIME_DISPLAY_ENABLED=false", "AMD_TIME_DISPLAY_HIDDEN_FOR_SEVERITIES=info, warning,"},
+			check: func(t *testing.T, config Config) {
+				if config.TimeDisplay.Enabled {
+					t.Errorf("timeDisplay.enabled = true")
+				}
+				if !reflect.DeepEqual(config.TimeDisplay.HiddenForSeverities, []string{"info", "warning"}) {
+					t.Errorf("timeDisplay.hiddenForSeverities = %q", config.TimeDisplay.HiddenForSeverities)
+				}
+			},
+		},
+		{
+			name:    "int",
+			environ: []string{"AMD_HISTORY_MAX_ENTRIES=42"},
+			check: func(t *testing.T, config Config) {
+				if config.History.MaxEntries != 42 {
+					t.Errorf("history.maxEntries = %d", config.History.MaxEntries)
+				}
+			},
+		},
+		{
+			name: "channels",
+			environ: []string{
+				"AMD_CHANNEL_TEAM_GO_WEBHOOK_URL=https://discord.com/api/webhooks/1",
+				"AMD_CHANNEL_TEAM_GO_WEBHOOK_URL_FILE=/run/secrets/team-go",
+				"AMD_CHANNEL_TEAM_GO_ROLES_TO_MENTION=<@&1>,<@&2>",
+				"AMD_CHANNEL_DEFAULT_NAME=default",
+			},
+			check: func(t *testing.T, config Config) {
+				channel := config.DiscordChannels["team-go"]
+				if channel.WebhookURL.Value() != "https://discord.com/api/webhooks/1" ||
+					channel.WebhookURLFile != "/run/secrets/team-go" ||
+					!reflect.DeepEqual(channel.RolesToMention, []string{"<@&1>", "<@&2>"}) {
+					t.Errorf("channels[team-go] = %+v", channel)
+				}
+				if config.DiscordChannels["default"].Name != "default" {
+					t.Errorf("channels[default] = %+v", config.DiscordChannels["default"])
+				}
+			},
+		},
+		{
+			name:    "other prefixes are ignored",
+			environ: []string{"USERNAME=root", "AMD=", "AMD_UNKNOWN=value"},
+			check: func(t *testing.T, config Config) {
+				if config.Username != defaultConfig.Username {
+					t.Errorf("username = %q", config.Username)
+				}
+			},
+		},
+		{
+			name:    "invalid bool",
+			environ: []string{"AMD_TIME_DISPLAY_ENABLED=maybe"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid int",
+			environ: []string{"AMD_HISTORY_MAX_ENTRIES=many"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := defaultConfig
+			config.DiscordChannels = nil
+
+			err := applyEnv(&config, test.environ)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("applyEnv() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if err == nil {
+				test.check(t, config)
+			}
+		})
+	}
+}
+
+func TestConfigFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantPath string
+		check    func(t *testing.T, config Config)
+	}{
+		{
+			name:     "config path",
+			args:     []string{"--config", "/etc/alertmanager-discord"},
+			wantPath: "/etc/alertmanager-discord",
+		},
+		{
+			name: "nested fields",
+			args: []string{"--username=Alertmanager", "--history.max-entries", "7", "--grafana-image.width=800"},
+			check: func(t *testing.T, config Config) {
+				if config.Username != "Alertmanager" || config.History.MaxEntries != 7 || config.GrafanaImage.Width != 800 {
+					t.Errorf("username = %q, history = %+v, grafanaImage.width = %d",
+						config.Username, config.History, config.GrafanaImage.Width)
+				}
+			},
+		},
+		{
+			name: "bool without value",
+			args: []string{"--alert-details.enabled"},
+			check: func(t *testing.T, config Config) {
+				if !config.AlertDetails.Enabled {
+					t.Errorf("alertDetails.enabled = false")
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := newConfigFlags("test")
+			if err := flags.flagSet.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			if flags.configPath != test.wantPath {
+				t.Errorf("configPath = %q, want %q", flags.configPath, test.wantPath)
+			}
+
+			config := defaultConfig
+			if err := flags.apply(&config); err != nil {
+				t.Fatalf("apply() error = %v", err)
+			}
+			if test.check != nil {
+				test.check(t, config)
+			}
+		})
+	}
+}
+
+func TestForwardFlags(t *testing.T) {
+	flagSet := flag.NewFlagSet("test-channel", flag.ContinueOnError)
+	count := flagSet.Int("count", 1, "Number of test alerts")
+	configArgs := ForwardFlags(flagSet)
+
+	args := []string{"--count", "3", "--config", "config.yaml", "--username", "Alertmanager", "--alert-details.enabled"}
+	if err := flagSet.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	if *count != 3 {
+		t.Errorf("count = %d, want 3", *count)
+	}
+	want := []string{"--alert-details.enabled=true", "--config=config.yaml", "--username=Alertmanager"}
+	if got := configArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("configArgs() = %v, want %v", got, want)
+	}
+}
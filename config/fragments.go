@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/imdario/mergo"
+)
+
+// configFragment is a parsed config file, with the keys it defines
+type configFragment struct {
+	file        string
+	config      Config
+	keys        []string
+	channelKeys []string
+}
+
+// loadConfiguration loads a single config file, or merges the fragments of a
+// directory or glob in lexical order. Channels are merged by key, any other
+// top level setting must be defined by a single fragment. Defining a channel
+// or a setting in more than one fragment is an error
+func loadConfiguration(path string) (Config, error) {
+	files, err := listConfigurationFiles(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var merged Config
+	keyFiles := map[string]string{}
+	channelFiles := map[string]string{}
+
+	for _, file := range files {
+		fragment, err := loadConfigurationFile(file)
+		if err != nil {
+			return Config{}, err
+		}
+
+		for _, key := range fragment.keys {
+			if key == "channels" {
+				continue
+			}
+			if previousFile, ok := keyFiles[key]; ok {
+				return Config{}, fmt.Errorf(
+					"config.loadConfiguration: %q is defined in both %s and %s",
+					key, previousFile, file)
+			}
+			keyFiles[key] = file
+		}
+
+		for _, channelKey := range fragment.channelKeys {
+			if previousFile, ok := channelFiles[channelKey]; ok {
+				return Config{}, fmt.Errorf(
+					"config.loadConfiguration: Channel %q is defined in both %s and %s",
+					channelKey, previousFile, file)
+			}
+			channelFiles[channelKey] = file
+		}
+
+		if err := mergo.Merge(&merged, fragment.config); err != nil {
+			return Config{}, fmt.Errorf("config.loadConfiguration: %s: \n%+v", file, err)
+		}
+	}
+
+	return merged, nil
+}
+
+// listConfigurationFiles returns the config files in path, in lexical order.
+// A directory lists its .json, .yaml and .yml files, skipping hidden ones
+// such as the "..data" entries of Kubernetes volumes
+func listConfigurationFiles(path string) ([]string, error) {
+	if strings.ContainsAny(path, "*?[") {
+		files, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("config.listConfigurationFiles: Invalid glob %s \n%+v", path, err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("config.listConfigurationFiles: No config files match %s", path)
+		}
+		sort.Strings(files)
+		return files, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("config.listConfigurationFiles: \n%+v", err)
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("config.listConfigurationFiles: \n%+v", err)
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		switch filepath.Ext(name) {
+		case ".json", ".yaml", ".yml":
+			files = append(files, filepath.Join(path, name))
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("config.listConfigurationFiles: No config files found in %s", path)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
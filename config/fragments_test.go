@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigurationFragments(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		path    string
+		wantErr string
+		check   func(t *testing.T, config Config)
+	}{
+		{
+			name: "channels are merged by key",
+			files: map[string]string{
+				"10-global.yaml": "username: Alertmanager\n",
+				"20-team-a.yaml": "channels:\n  team-a:\n    name: team-a\n",
+				"30-team-b.json": `{"channels": {"team-b": {"name": "team-b"}}}`,
+			},
+			check: func(t *testing.T, config Config) {
+				if config.Username != "Alertmanager" {
+					t.Errorf("username = %q", config.Username)
+				}
+				if len(config.DiscordChannels) != 2 ||
+					config.DiscordChannels["team-a"].Name != "team-a" ||
+					config.DiscordChannels["team-b"].Name != "team-b" {
+					t.Errorf("channels = %+v", config.DiscordChannels)
+				}
+			},
+		},
+		{
+			name: "hidden and unrelated files are skipped",
+			files: map[string]string{
+				"config.yaml":      "username: Alertmanager\n",
+				"..data.yaml":      "username: Hidden\n",
+				"README.md":        "username: Readme\n",
+				".hidden/ignored":  "",
+				"other.yml":        "avatarURL: https://example.com/avatar.png\n",
+				"..2024_01_01.yml": "username: Hidden\n",
+			},
+			check: func(t *testing.T, config Config) {
+				if config.Username != "Alertmanager" || config.AvatarURL != "https://example.com/avatar.png" {
+					t.Errorf("username = %q, avatarURL = %q", config.Username, config.AvatarURL)
+				}
+			},
+		},
+		{
+			name: "glob",
+			files: map[string]string{
+				"team-a.yaml": "channels:\n  team-a:\n    name: team-a\n",
+				"team-b.yaml": "channels:\n  team-b:\n    name: team-b\n",
+				"other.yaml":  "username: Other\n",
+			},
+			path: "team-*.yaml",
+			check: func(t *testing.T, config Config) {
+				if len(config.DiscordChannels) != 2 || config.Username != "" {
+					t.Errorf("channels = %+v, username = %q", config.DiscordChannels, config.Username)
+				}
+			},
+		},
+		{
+			name: "setting defined twice",
+			files: map[string]string{
+				"a.yaml": "username: A\n",
+				"b.yaml": "username: B\n",
+			},
+			wantErr: `"username" is defined in both`,
+		},
+		{
+			name: "channel defined twice",
+			files: map[string]string{
+				"a.yaml": "channels:\n  team-a:\n    name: a\n",
+				"b.yaml": "channels:\n  team-a:\n    name: b\n",
+			},
+			wantErr: `Channel "team-a" is defined in both`,
+		},
+		{
+			name:    "empty directory",
+			files:   map[string]string{"README.md": ""},
+			wantErr: "No config files found",
+		},
+		{
+			name:    "glob without matches",
+			files:   map[string]string{"config.yaml": ""},
+			path:    "*.json",
+			wantErr: "No config files match",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, contents := range test.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			config, err := loadConfiguration(filepath.Join(dir, test.path))
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("loadConfiguration() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadConfiguration() error = %v", err)
+			}
+			test.check(t, config)
+		})
+	}
+}
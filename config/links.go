@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"text/template"
+)
+
+var linkTemplateFuncs = template.FuncMap{
+	"queryEscape": url.QueryEscape,
+	"pathEscape":  url.PathEscape,
+}
+
+// ParseLinkTemplate parses the URL template of a link, with the functions
+// available to link templates. Missing labels and annotations evaluate to an
+// empty string
+func ParseLinkTemplate(linkConfig LinkConfig) (*template.Template, error) {
+	return template.New(linkConfig.Text).
+		Funcs(linkTemplateFuncs).
+		Option("missingkey=zero").
+		Parse(linkConfig.URL)
+}
+
+// validateLinks checks the positions and URL templates of the links, which
+// would otherwise be skipped when sending
+func validateLinks(config Config) error {
+	if config.DashboardLink.Enabled && !validLinkPosition(config.DashboardLink.Position) {
+		return fmt.Errorf("config.validateLinks: Invalid dashboardLink.position %q", config.DashboardLink.Position)
+	}
+
+	if config.GeneratorLink.Enabled && !validLinkPosition(config.GeneratorLink.Position) {
+		return fmt.Errorf("config.validateLinks: Invalid generatorLink.position %q", config.GeneratorLink.Position)
+	}
+
+	for _, linkConfig := range config.Links {
+		if !validLinkPosition(linkConfig.Position) {
+			return fmt.Errorf("config.validateLinks: Link %s: Invalid position %q", linkConfig.Text, linkConfig.Position)
+		}
+		if _, err := ParseLinkTemplate(linkConfig); err != nil {
+			return fmt.Errorf("config.validateLinks: Link %s: Invalid url template \n%+v", linkConfig.Text, err)
+		}
+	}
+
+	return nil
+}
+
+// validLinkPosition tells whether a link can be shown at position
+func validLinkPosition(position string) bool {
+	switch position {
+	case "content", "embed_top", "embed_bottom", "field":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package config
+
+import "testing"
+
+func TestValidateLinks(t *testing.T) {
+	link := func(url, position string) LinkConfig {
+		return LinkConfig{Text: "Runbook", URL: url, Position: position}
+	}
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"defaults", defaultConfig, false},
+		{"every position", Config{Links: []LinkConfig{
+			link("{{ .Annotations.runbook_url }}", "content"),
+			link("{{ .Annotations.runbook_url }}", "embed_top"),
+			link("{{ .Annotations.runbook_url }}", "embed_bottom"),
+			link(`https://logs.example.com/?query={{ queryEscape .Labels.pod }}&ns={{ pathEscape .Labels.namespace }}`, "field"),
+		}}, false},
+		{"invalid position", Config{Links: []LinkConfig{link("{{ .Annotations.runbook_url }}", "embed")}}, true},
+		{"no position", Config{Links: []LinkConfig{link("{{ .Annotations.runbook_url }}", "")}}, true},
+		{"invalid template", Config{Links: []LinkConfig{link("{{ .Annotations.runbook_url", "field")}}, true},
+		{"unknown function", Config{Links: []LinkConfig{link("{{ urlEscape .Labels.pod }}", "field")}}, true},
+		{"invalid dashboardLink position", Config{
+			DashboardLink: DashboardLinkConfig{Enabled: true, Position: "top"},
+		}, true},
+		{"disabled dashboardLink", Config{DashboardLink: DashboardLinkConfig{Position: "top"}}, false},
+		{"invalid generatorLink position", Config{
+			GeneratorLink: GeneratorLinkConfig{Enabled: true, Position: "bottom"},
+		}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateLinks(test.config)
+			if (err != nil) != test.wantErr {
+				t.Errorf("validateLinks() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
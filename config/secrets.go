@@ -0,0 +1,226 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/kolesaev/alertmanager-discord/redact"
+	"gopkg.in/yaml.v3"
+)
+
+// envReferenceRegexp matches "${VAR}" references, and "$${VAR}" escapes
+var envReferenceRegexp = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// envExpander replaces "${VAR}" references in the values of a config file
+// with the value of the environment variable. Use "$${VAR}" to keep a literal
+// "${VAR}". Keys and comments are left untouched
+type envExpander struct {
+	missing []string
+}
+
+// expandEnvYAML expands the references in the scalar values of a YAML file
+func expandEnvYAML(contents []byte) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(contents, &document); err != nil {
+		return nil, fmt.Errorf("config.expandEnvYAML: \n%+v", err)
+	}
+	if document.Kind == 0 {
+		return contents, nil
+	}
+
+	expander := &envExpander{}
+	expander.expandNode(&document)
+	if err := expander.err(); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(&document)
+}
+
+// expandEnvJSON expands the references in the string values of a JSON file
+func expandEnvJSON(contents []byte) ([]byte, error) {
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("config.expandEnvJSON: \n%+v", err)
+	}
+
+	expander := &envExpander{}
+	document = expander.expandValue(document)
+	if err := expander.err(); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(document)
+}
+
+func (e *envExpander) expandNode(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		expanded := e.expand(node.Value)
+		if expanded == node.Value {
+			return
+		}
+		node.Value = expanded
+		// Plain values are resolved again, so "${PORT}" can still be a number
+		if node.Style == 0 {
+			node.Tag = ""
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			e.expandNode(node.Content[i])
+		}
+	default:
+		for _, child := range node.Content {
+			e.expandNode(child)
+		}
+	}
+}
+
+func (e *envExpander) expandValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case string:
+		return e.expand(typed)
+	case map[string]interface{}:
+		for key, child := range typed {
+			typed[key] = e.expandValue(child)
+		}
+	case []interface{}:
+		for i, child := range typed {
+			typed[i] = e.expandValue(child)
+		}
+	}
+	return value
+}
+
+func (e *envExpander) expand(value string) string {
+	return envReferenceRegexp.ReplaceAllStringFunc(value, func(reference string) string {
+		if strings.HasPrefix(reference, "$$") {
+			return reference[1:]
+		}
+
+		name := envReferenceRegexp.FindStringSubmatch(reference)[1]
+		envValue, ok := os.LookupEnv(name)
+		if !ok {
+			e.missing = append(e.missing, name)
+			return reference
+		}
+		return envValue
+	})
+}
+
+func (e *envExpander) err() error {
+	if len(e.missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf(
+		"config.expandEnv: Undefined environment variables referenced in config: %s",
+		strings.Join(e.missing, ", "))
+}
+
+// readSecret returns value, or the contents of file when it's set. Files are
+// read on every call, so mounted secrets can be rotated without a restart
+func readSecret(value, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("config.readSecret: Error reading secret file \n%+v", err)
+	}
+
+	return strings.TrimSpace(string(contents)), nil
+}
+
+// GetWebhookURL returns the channel's webhook URL, read from WebhookURLFile
+// when it's set
+func (c DiscordChannel) GetWebhookURL() (string, error) {
+	return readSecret(c.WebhookURL.Value(), c.WebhookURLFile)
+}
+
+// GetAPIToken returns the Grafana API token, read from APITokenFile when it's
+// set
+func (c GrafanaImageConfig) GetAPIToken() (string, error) {
+	return readSecret(c.APIToken.Value(), c.APITokenFile)
+}
+
+// GetToken returns the admin API token, read from TokenFile when it's set
+func (c AdminConfig) GetToken() (string, error) {
+	return readSecret(c.Token.Value(), c.TokenFile)
+}
+
+// validateSecretFiles checks that every configured secret file can be read,
+// so a wrong mount fails at startup instead of at the first alert
+func validateSecretFiles(config Config) error {
+	for channelName, channel := range config.DiscordChannels {
+		if _, err := channel.GetWebhookURL(); err != nil {
+			return fmt.Errorf("config.validateSecretFiles: Channel %s: \n%+v", channelName, err)
+		}
+	}
+
+	if _, err := config.GrafanaImage.GetAPIToken(); err != nil {
+		return fmt.Errorf("config.validateSecretFiles: grafanaImage: \n%+v", err)
+	}
+
+	if _, err := config.Admin.GetToken(); err != nil {
+		return fmt.Errorf("config.validateSecretFiles: admin: \n%+v", err)
+	}
+
+	return nil
+}
+
+// Secret is a config value that must not be logged, such as a token. It's
+// masked when formatted or marshaled, use Value to get the actual value
+type Secret string
+
+// Value returns the unmasked secret
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redact.Mask
+}
+
+// MarshalYAML masks the secret in config dumps
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// MarshalJSON masks the secret in config dumps
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// WebhookURL is a Discord webhook URL. Its token is masked when formatted or
+// marshaled, use Value to get the actual URL
+type WebhookURL string
+
+// Value returns the unmasked webhook URL
+func (u WebhookURL) Value() string {
+	return string(u)
+}
+
+func (u WebhookURL) String() string {
+	return redact.String(string(u))
+}
+
+// MarshalYAML masks the token in config dumps
+func (u WebhookURL) MarshalYAML() (interface{}, error) {
+	return u.String(), nil
+}
+
+// MarshalJSON masks the token in config dumps
+func (u WebhookURL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.String())
+}
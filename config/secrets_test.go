@@ -0,0 +1,134 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadExampleConfig(t *testing.T) {
+	t.Setenv("TEAM_GO_WEBHOOK_URL", "https://discord.com/api/webhooks/team-go")
+
+	config, err := loadConfiguration("../config.example.yaml")
+	if err != nil {
+		t.Fatalf("loadConfiguration() error = %v", err)
+	}
+
+	if got := config.DiscordChannels["team-go"].WebhookURL.Value(); got != "https://discord.com/api/webhooks/team-go" {
+		t.Errorf("team-go webhookURL = %q, want the TEAM_GO_WEBHOOK_URL value", got)
+	}
+	if err := validateResolvedPolicies(config); err != nil {
+		t.Errorf("validateResolvedPolicies() error = %v", err)
+	}
+	if err := validateSortKeys(config.Sort); err != nil {
+		t.Errorf("validateSortKeys() error = %v", err)
+	}
+}
+
+func TestLoadConfigurationFileExpandsEnv(t *testing.T) {
+	t.Setenv("AMD_TEST_WEBHOOK", "https://discord.com/api/webhooks/1")
+	t.Setenv("AMD_TEST_PORT", "9095")
+	t.Setenv("AMD_TEST_ENABLED", "true")
+	t.Setenv("AMD_TEST_VALUE", "a: #b")
+
+	tests := []struct {
+		name     string
+		file     string
+		contents string
+		wantErr  string
+		check    func(t *testing.T, config Config)
+	}{
+		{
+			name: "yaml values",
+			file: "config.yaml",
+			contents: `
+# "${IN_COMMENT}" and "$${IN_COMMENT}" are left alone
+listenAddress: ":${AMD_TEST_PORT}"
+username: ${AMD_TEST_VALUE} # ${TRAILING_COMMENT}
+channels:
+  default:
+    name: "$${AMD_TEST_WEBHOOK}"
+    webhookURL: ${AMD_TEST_WEBHOOK}
+`,
+			check: func(t *testing.T, config Config) {
+				if config.ListenAddress != ":9095" {
+					t.Errorf("listenAddress = %q", config.ListenAddress)
+				}
+				if config.Username != "a: #b" {
+					t.Errorf("username = %q", config.Username)
+				}
+				channel := config.DiscordChannels["default"]
+				if channel.Name != "${AMD_TEST_WEBHOOK}" {
+					t.Errorf("escaped name = %q", channel.Name)
+				}
+				if channel.WebhookURL.Value() != "https://discord.com/api/webhooks/1" {
+					t.Errorf("webhookURL = %q", channel.WebhookURL.Value())
+				}
+			},
+		},
+		{
+			name:     "yaml typed values",
+			file:     "config.yaml",
+			contents: "history:\n  enabled: ${AMD_TEST_ENABLED}\n  maxEntries: ${AMD_TEST_PORT}\n",
+			check: func(t *testing.T, config Config) {
+				if !config.History.Enabled || config.History.MaxEntries != 9095 {
+					t.Errorf("history = %+v", config.History)
+				}
+			},
+		},
+		{
+			name:     "yaml keys are not expanded",
+			file:     "config.yaml",
+			contents: "channels:\n  ${AMD_TEST_PORT}:\n    name: default\n",
+			check: func(t *testing.T, config Config) {
+				if _, ok := config.DiscordChannels["${AMD_TEST_PORT}"]; !ok {
+					t.Errorf("channels = %v", config.DiscordChannels)
+				}
+			},
+		},
+		{
+			name:     "yaml undefined variable",
+			file:     "config.yaml",
+			contents: "username: ${AMD_TEST_UNDEFINED}\n",
+			wantErr:  "AMD_TEST_UNDEFINED",
+		},
+		{
+			name:     "json values",
+			file:     "config.json",
+			contents: `{"username": "${AMD_TEST_VALUE}", "history": {"maxEntries": 10}}`,
+			check: func(t *testing.T, config Config) {
+				if config.Username != "a: #b" || config.History.MaxEntries != 10 {
+					t.Errorf("username = %q, history = %+v", config.Username, config.History)
+				}
+			},
+		},
+		{
+			name:     "json undefined variable",
+			file:     "config.json",
+			contents: `{"username": "${AMD_TEST_UNDEFINED}"}`,
+			wantErr:  "AMD_TEST_UNDEFINED",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.file)
+			if err := os.WriteFile(path, []byte(test.contents), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			fragment, err := loadConfigurationFile(path)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("loadConfigurationFile() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadConfigurationFile() error = %v", err)
+			}
+			test.check(t, fragment.config)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// unknownSeverity is the severity of alerts without any severity label
+const unknownSeverity = "unknown"
+
+// defaultSeverityAliases are the aliases of the default severity values.
+// They're kept apart from defaultConfig, since a severity value set in the
+// config file replaces the default one as a whole
+var defaultSeverityAliases = map[string][]string{
+	"info":     {"information"},
+	"warning":  {"warn"},
+	"critical": {"crit"},
+}
+
+// Normalize returns the severity value meant by value: the value itself when
+// defined, then the value or alias matching case-insensitively. Values that
+// match nothing are returned as is
+func (d SeverityDefinition) Normalize(value string) string {
+	if value == "" {
+		return unknownSeverity
+	}
+
+	if _, ok := d.Values[value]; ok {
+		return value
+	}
+
+	// Sorted, so a value matching several severities always gets the same
+	severities := make([]string, 0, len(d.Values))
+	for severity := range d.Values {
+		severities = append(severities, severity)
+	}
+	sort.Strings(severities)
+
+	for _, severity := range severities {
+		if strings.EqualFold(severity, value) {
+			return severity
+		}
+	}
+
+	for _, severity := range severities {
+		for _, alias := range d.Values[severity].Aliases {
+			if strings.EqualFold(alias, value) {
+				return severity
+			}
+		}
+	}
+
+	return value
+}
+
+// NormalizeAll normalizes a list of severities, e.g. severitiesToMention
+func (d SeverityDefinition) NormalizeAll(values []string) []string {
+	if values == nil {
+		return nil
+	}
+
+	normalized := make([]string, len(values))
+	for i, value := range values {
+		normalized[i] = d.Normalize(value)
+	}
+	return normalized
+}
+
+// Of returns the effective severity of an alert with the given labels, from
+// the severity label or else the first fallback label set. It's "unknown"
+// when none is set
+func (d SeverityDefinition) Of(labels map[string]string) string {
+	for _, label := range append([]string{d.Label}, d.FallbackLabels...) {
+		if value := labels[label]; value != "" {
+			return d.Normalize(value)
+		}
+	}
+	return unknownSeverity
+}
+
+// addDefaultSeverityAliases sets the default aliases of the severity values
+// whose aliases aren't configured
+func addDefaultSeverityAliases(config *Config) {
+	for severity, aliases := range defaultSeverityAliases {
+		appearance, ok := config.Severity.Values[severity]
+		if !ok || appearance.Aliases != nil {
+			continue
+		}
+		appearance.Aliases = aliases
+		config.Severity.Values[severity] = appearance
+	}
+}
+
+// normalizeSeverityLists normalizes the severities configured for the
+// mentions, filters, time display, windows and resolved policies, so they
+// compare with the effective severities of the alerts
+func normalizeSeverityLists(config *Config) {
+	severity := config.Severity
+
+	config.SeveritiesToMention = severity.NormalizeAll(config.SeveritiesToMention)
+	config.SeveritiesToIgnoreWhenAlone = severity.NormalizeAll(config.SeveritiesToIgnoreWhenAlone)
+	config.TimeDisplay.HiddenForSeverities = severity.NormalizeAll(config.TimeDisplay.HiddenForSeverities)
+
+	for channelName, discordChannel := range config.DiscordChannels {
+		discordChannel.SeveritiesToMention = severity.NormalizeAll(discordChannel.SeveritiesToMention)
+		discordChannel.SeveritiesToIgnoreWhenAlone = severity.NormalizeAll(discordChannel.SeveritiesToIgnoreWhenAlone)
+		discordChannel.Resolved.Severities = severity.NormalizeAll(discordChannel.Resolved.Severities)
+
+		windows := make([]TimeWindow, len(discordChannel.Windows))
+		for i, window := range discordChannel.Windows {
+			window.Severities = severity.NormalizeAll(window.Severities)
+			windows[i] = window
+		}
+		if discordChannel.Windows != nil {
+			discordChannel.Windows = windows
+		}
+
+		config.DiscordChannels[channelName] = discordChannel
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testSeverityDefinition() SeverityDefinition {
+	return SeverityDefinition{
+		Label:          "severity",
+		FallbackLabels: []string{"level", "priority"},
+		Values: map[string]SeverityAppearance{
+			"info":     {Aliases: []string{"information"}},
+			"warning":  {Aliases: []string{"warn"}},
+			"critical": {Aliases: []string{"crit", "P1"}},
+			"Page":     {},
+		},
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	definition := testSeverityDefinition()
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"warning", "warning"},
+		{"WARNING", "warning"},
+		{"page", "Page"},
+		{"warn", "warning"},
+		{"Crit", "critical"},
+		{"p1", "critical"},
+		{"disaster", "disaster"},
+		{"", "unknown"},
+	}
+
+	for _, test := range tests {
+		if got := definition.Normalize(test.value); got != test.want {
+			t.Errorf("Normalize(%q) = %q, want %q", test.value, got, test.want)
+		}
+	}
+}
+
+func TestSeverityOf(t *testing.T) {
+	definition := testSeverityDefinition()
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		want   string
+	}{
+		{"severity label", map[string]string{"severity": "crit", "level": "info"}, "critical"},
+		{"first fallback label", map[string]string{"level": "warn", "priority": "P1"}, "warning"},
+		{"second fallback label", map[string]string{"priority": "P1"}, "critical"},
+		{"empty label skipped", map[string]string{"severity": "", "level": "info"}, "info"},
+		{"unknown value", map[string]string{"severity": "disaster"}, "disaster"},
+		{"no label", map[string]string{"alertname": "HighLatency"}, "unknown"},
+		{"no labels", nil, "unknown"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := definition.Of(test.labels); got != test.want {
+				t.Errorf("Of(%v) = %q, want %q", test.labels, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeAll(t *testing.T) {
+	definition := testSeverityDefinition()
+
+	if got := definition.NormalizeAll(nil); got != nil {
+		t.Errorf("NormalizeAll(nil) = %v, want nil", got)
+	}
+
+	got := definition.NormalizeAll([]string{"WARN", "information", "page"})
+	if want := []string{"warning", "info", "Page"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeAll() = %v, want %v", got, want)
+	}
+}
+
+func TestAddDefaultSeverityAliases(t *testing.T) {
+	config := Config{Severity: SeverityDefinition{Values: map[string]SeverityAppearance{
+		"info":     {},
+		"warning":  {Aliases: []string{"minor"}},
+		"critical": {Aliases: []string{}},
+		"page":     {},
+	}}}
+
+	addDefaultSeverityAliases(&config)
+
+	want := map[string][]string{
+		"info":     {"information"},
+		"warning":  {"minor"},
+		"critical": {},
+		"page":     nil,
+	}
+	for severity, aliases := range want {
+		if got := config.Severity.Values[severity].Aliases; !reflect.DeepEqual(got, aliases) {
+			t.Errorf("Aliases of %s = %#v, want %#v", severity, got, aliases)
+		}
+	}
+}
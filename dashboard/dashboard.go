@@ -0,0 +1,189 @@
+package dashboard
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/discord"
+	"github.com/kolesaev/alertmanager-discord/health"
+	"github.com/kolesaev/alertmanager-discord/history"
+	"github.com/kolesaev/alertmanager-discord/state"
+)
+
+// The templates hold their own styles, so the dashboard doesn't load any
+// external asset and works in air-gapped clusters
+//
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+var templateFuncs = template.FuncMap{
+	"embedColor": func(color int) template.CSS {
+		return template.CSS(fmt.Sprintf("#%06x", color))
+	},
+	"formatTime": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Local().Format("2006-01-02 15:04:05 MST")
+	},
+	"formatDuration": func(d time.Duration) string {
+		return d.Round(time.Millisecond).String()
+	},
+	"deref": func(b *bool) bool {
+		return b != nil && *b
+	},
+}
+
+// Dashboard serves the HTML pages
+type Dashboard struct {
+	configs   config.Config
+	tracker   *health.Tracker
+	store     history.Store
+	state     *state.Tracker
+	templates map[string]*template.Template
+}
+
+// Notification is a history entry with its parsed message
+type Notification struct {
+	history.Entry
+	Rendered discord.WebhookParams
+}
+
+// ChannelView is a channel with its health and latest notifications
+type ChannelView struct {
+	health.ChannelStatus
+	Notifications []Notification
+}
+
+// ActiveAlertView is an active alert with its effective severity
+type ActiveAlertView struct {
+	state.Alert
+	Severity string
+}
+
+// New parses the templates of the dashboard
+func New(
+	configs config.Config,
+	tracker *health.Tracker,
+	store history.Store,
+	alertState *state.Tracker) (*Dashboard, error) {
+
+	dashboard := &Dashboard{
+		configs:   configs,
+		tracker:   tracker,
+		store:     store,
+		state:     alertState,
+		templates: map[string]*template.Template{},
+	}
+
+	for _, page := range []string{"index.html", "preview.html"} {
+		pageTemplate, err := template.New(page).Funcs(templateFuncs).
+			ParseFS(templateFiles, "templates/layout.html", "templates/embeds.html", "templates/"+page)
+		if err != nil {
+			return nil, fmt.Errorf("dashboard.New: Error parsing %s \n%+v", page, err)
+		}
+		dashboard.templates[page] = pageTemplate
+	}
+
+	return dashboard, nil
+}
+
+// Index shows the channels with their health and latest notifications, and
+// the active alerts
+func (d *Dashboard) Index(c *gin.Context) {
+	limit := d.configs.Dashboard.NotificationsPerChannel
+
+	channels := []ChannelView{}
+	for _, status := range d.tracker.Channels() {
+		entries, err := d.store.Query(history.Filter{Channel: status.Name, Limit: limit})
+		if err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		channels = append(channels, ChannelView{
+			ChannelStatus: status,
+			Notifications: parseNotifications(entries),
+		})
+	}
+
+	activeAlerts := []ActiveAlertView{}
+	for _, alert := range d.state.Active("") {
+		activeAlerts = append(activeAlerts, ActiveAlertView{
+			Alert:    alert,
+			Severity: d.configs.Severity.Of(alert.Labels),
+		})
+	}
+
+	d.render(c, "index.html", gin.H{
+		"Channels":     channels,
+		"ActiveAlerts": activeAlerts,
+	})
+}
+
+// Preview renders a pasted Alertmanager payload with the current config,
+// without sending it
+func (d *Dashboard) Preview(c *gin.Context) {
+	data := gin.H{
+		"Channels": d.channelNames(),
+		"Channel":  c.PostForm("channel"),
+		"Payload":  c.PostForm("payload"),
+	}
+
+	if c.Request.Method == http.MethodPost {
+		preview, err := d.preview(c, c.PostForm("channel"), c.PostForm("payload"))
+		if err != nil {
+			data["Error"] = err.Error()
+		} else {
+			data["Preview"] = preview
+		}
+	}
+
+	d.render(c, "preview.html", data)
+}
+
+func (d *Dashboard) preview(c *gin.Context, channelName, payload string) (discord.Preview, error) {
+	var body alertmanager.MessageBody
+	if err := json.Unmarshal([]byte(payload), &body); err != nil {
+		return discord.Preview{}, fmt.Errorf("Invalid payload: %v", err)
+	}
+
+	return discord.PreviewAlerts(c.Request.Context(), channelName, body, d.configs)
+}
+
+func (d *Dashboard) channelNames() []string {
+	names := make([]string, 0, len(d.configs.DiscordChannels))
+	for name := range d.configs.DiscordChannels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (d *Dashboard) render(c *gin.Context, page string, data gin.H) {
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := d.templates[page].ExecuteTemplate(c.Writer, "layout", data); err != nil {
+		c.Error(err)
+	}
+}
+
+func parseNotifications(entries []history.Entry) []Notification {
+	notifications := make([]Notification, len(entries))
+	for i, entry := range entries {
+		notifications[i].Entry = entry
+		if len(entry.Message) > 0 {
+			// A message that can't be parsed is shown as empty
+			json.Unmarshal(entry.Message, &notifications[i].Rendered)
+		}
+	}
+	return notifications
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/dashboard"
+	"github.com/kolesaev/alertmanager-discord/health"
+	"github.com/kolesaev/alertmanager-discord/history"
+	"github.com/kolesaev/alertmanager-discord/state"
+)
+
+// registerDashboardRoutes adds the web dashboard and its preview form,
+// protected by the admin token, since the preview renders Grafana panels
+func registerDashboardRoutes(
+	router *gin.Engine,
+	tracker *health.Tracker,
+	store history.Store,
+	alertState *state.Tracker,
+	configs config.Config) error {
+
+	pages, err := dashboard.New(configs, tracker, store, alertState)
+	if err != nil {
+		return err
+	}
+
+	requireToken := requireAdminToken(configs.Admin)
+	router.GET("/dashboard", requireToken, pages.Index)
+	router.GET("/dashboard/preview", requireToken, pages.Preview)
+	router.POST("/dashboard/preview", requireToken, pages.Preview)
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/health"
+	"github.com/kolesaev/alertmanager-discord/history"
+	"github.com/kolesaev/alertmanager-discord/state"
+)
+
+func TestDashboardRoutesNeedAdminToken(t *testing.T) {
+	configs := loadTestConfig(t, `
+admin:
+  token: secret
+dashboard:
+  enabled: true
+`)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	err := registerDashboardRoutes(router, health.NewTracker(), history.NewMemoryStore(10, 0), state.NewTracker(0, ""), configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		authorize func(request *http.Request)
+		want      int
+	}{
+		{"index without token", http.MethodGet, "/dashboard", func(*http.Request) {}, http.StatusUnauthorized},
+		{"preview without token", http.MethodPost, "/dashboard/preview", func(*http.Request) {}, http.StatusUnauthorized},
+		{"wrong basic auth", http.MethodGet, "/dashboard", func(request *http.Request) {
+			request.SetBasicAuth("admin", "other")
+		}, http.StatusUnauthorized},
+		{"basic auth", http.MethodGet, "/dashboard", func(request *http.Request) {
+			request.SetBasicAuth("admin", "secret")
+		}, http.StatusOK},
+		{"bearer", http.MethodGet, "/dashboard/preview", func(request *http.Request) {
+			request.Header.Set("Authorization", "Bearer secret")
+		}, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, test.path, nil)
+			test.authorize(request)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.want {
+				t.Errorf("Expected status %d, got %d", test.want, recorder.Code)
+			}
+			if test.want == http.StatusUnauthorized && recorder.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("Expected a WWW-Authenticate header, so browsers prompt for the token")
+			}
+		})
+	}
+}
+
+func TestDashboardShowsEffectiveSeverity(t *testing.T) {
+	configs := loadTestConfig(t, `
+admin:
+  token: secret
+dashboard:
+  enabled: true
+severity:
+  fallbackLabels: [priority]
+`)
+
+	alertState := state.NewTracker(0, "")
+	err := alertState.Update("default", []alertmanager.Alert{
+		{Status: "firing", Labels: map[string]string{"alertname": "HighLatency", "severity": "CRIT"}},
+		{Status: "firing", Labels: map[string]string{"alertname": "DiskFilling", "priority": "warn"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	err = registerDashboardRoutes(router, health.NewTracker(), history.NewMemoryStore(10, 0), alertState, configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	request.Header.Set("Authorization", "Bearer secret")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	body := recorder.Body.String()
+	for _, want := range []string{"<td>critical</td>", "<td>warning</td>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in the active alerts", want)
+		}
+	}
+	if strings.Contains(body, "<td>CRIT</td>") {
+		t.Errorf("Expected the raw severity label not to be shown")
+	}
+}
@@ -0,0 +1,254 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+// Discord's limits for a single webhook message
+const (
+	maxEmbedsPerMessage     = 10
+	maxEmbedDescriptionSize = 4096
+	maxEmbedsTotalSize      = 6000
+	maxContentSize          = 2000
+)
+
+// attachAlertDetails fits the message in Discord's limits, replacing the
+// embeds that don't fit with a summary embed, and attaches the full list of
+// alerts as a file when the message overflows or when configured to always
+// do so.
+func attachAlertDetails(
+	message *WebhookParams,
+	alertmanagerBodyInfo alertmanager.MessageBodyInfo,
+	configs config.Config) (*File, error) {
+
+	detailsConfig := configs.AlertDetails
+	if !detailsConfig.Enabled {
+		return nil, nil
+	}
+
+	overflows := messageOverflows(message)
+	if !overflows && detailsConfig.When != "always" {
+		return nil, nil
+	}
+
+	alerts := collectAlerts(alertmanagerBodyInfo)
+
+	file, err := renderAlertDetails(alerts, detailsConfig.Format)
+	if err != nil {
+		return nil, fmt.Errorf("discord.attachAlertDetails: Error rendering alert details \n%+v", err)
+	}
+
+	if overflows {
+		shown := fitEmbeds(message)
+		message.Embeds = append(message.Embeds, MessageEmbed{
+			Description: fmt.Sprintf(
+				"Showing %d of %d groups. The full list of %d alerts is attached in `%s`.",
+				shown, countGroups(alertmanagerBodyInfo), len(alerts), file.Name),
+			Color: configs.Severity.Values["unknown"].Color,
+		})
+	}
+
+	return &file, nil
+}
+
+func messageOverflows(message *WebhookParams) bool {
+	if len(message.Embeds) > maxEmbedsPerMessage ||
+		utf8.RuneCountInString(message.Content) > maxContentSize {
+		return true
+	}
+
+	total := utf8.RuneCountInString(message.Content)
+	for _, embed := range message.Embeds {
+		if utf8.RuneCountInString(embed.Description) > maxEmbedDescriptionSize {
+			return true
+		}
+		total += embedSize(embed)
+	}
+
+	return total > maxEmbedsTotalSize
+}
+
+// fitEmbeds keeps the leading embeds that fit in the limits, leaving room
+// for the summary embed, and returns how many were kept. The message content
+// counts toward the total size, and is cut to its own limit
+func fitEmbeds(message *WebhookParams) int {
+	const summaryReserve = 200
+
+	if utf8.RuneCountInString(message.Content) > maxContentSize {
+		message.Content = truncateContent(message.Content, maxContentSize)
+	}
+
+	fitted := []MessageEmbed{}
+	total := summaryReserve + utf8.RuneCountInString(message.Content)
+
+	for _, embed := range message.Embeds {
+		if len(fitted) == maxEmbedsPerMessage-1 {
+			break
+		}
+
+		if utf8.RuneCountInString(embed.Description) > maxEmbedDescriptionSize {
+			embed.Description = truncateDescription(embed.Description, maxEmbedDescriptionSize)
+		}
+
+		size := embedSize(embed)
+		if total+size > maxEmbedsTotalSize {
+			break
+		}
+
+		total += size
+		fitted = append(fitted, embed)
+	}
+
+	message.Embeds = fitted
+	return len(fitted)
+}
+
+// truncateDescription cuts a description longer than limit characters
+func truncateDescription(description string, limit int) string {
+	const suffix = "\n…"
+	const codeBlock = "```"
+
+	end := limit - utf8.RuneCountInString(suffix) - len(codeBlock)
+	cut := string([]rune(description)[:end])
+	// Don't leave a code block open
+	if strings.Count(cut, codeBlock)%2 == 1 {
+		cut += codeBlock
+	}
+
+	return cut + suffix
+}
+
+// truncateContent cuts the content to limit characters, at the end of a line
+// so no link is left broken
+func truncateContent(content string, limit int) string {
+	const suffix = "\n…"
+
+	cut := string([]rune(content)[:limit-utf8.RuneCountInString(suffix)])
+	if end := strings.LastIndex(cut, "\n"); end >= 0 {
+		cut = cut[:end]
+	}
+
+	return cut + suffix
+}
+
+// embedSize is the number of characters of the embed counted by Discord
+// toward the total size of the message
+func embedSize(embed MessageEmbed) int {
+	size := utf8.RuneCountInString(embed.Title) + utf8.RuneCountInString(embed.Description)
+	for _, field := range embed.Fields {
+		size += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	return size
+}
+
+func countGroups(alertmanagerBodyInfo alertmanager.MessageBodyInfo) int {
+	return len(alertmanagerBodyInfo.FiringAlertsGroupedByName) +
+		len(alertmanagerBodyInfo.ResolvedAlertsGroupedByName)
+}
+
+// collectAlerts lists firing alerts and then resolved ones, each ordered by
+// group name so the attachment is stable between sends
+func collectAlerts(alertmanagerBodyInfo alertmanager.MessageBodyInfo) []alertmanager.Alert {
+	alerts := []alertmanager.Alert{}
+
+	for _, groups := range []alertmanager.AlertsGroupedByLabel{
+		alertmanagerBodyInfo.FiringAlertsGroupedByName,
+		alertmanagerBodyInfo.ResolvedAlertsGroupedByName,
+	} {
+		names := make([]string, 0, len(groups))
+		for name := range groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			alerts = append(alerts, groups[name].Alerts...)
+		}
+	}
+
+	return alerts
+}
+
+func renderAlertDetails(alerts []alertmanager.Alert, format string) (File, error) {
+	switch format {
+	case "csv":
+		data, err := renderAlertDetailsCSV(alerts)
+		return File{Name: "alerts.csv", ContentType: "text/csv", Data: data}, err
+	case "json":
+		data, err := json.MarshalIndent(alerts, "", "  ")
+		return File{Name: "alerts.json", ContentType: "application/json", Data: data}, err
+	case "markdown":
+		data := renderAlertDetailsMarkdown(alerts)
+		return File{Name: "alerts.md", ContentType: "text/markdown", Data: data}, nil
+	default:
+		return File{}, fmt.Errorf("discord.renderAlertDetails: No matching format for %s", format)
+	}
+}
+
+func renderAlertDetailsCSV(alerts []alertmanager.Alert) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	rows := [][]string{{"status", "labels", "annotations", "startsAt", "endsAt", "fingerprint"}}
+	for _, alert := range alerts {
+		rows = append(rows, []string{
+			alert.Status,
+			formatKeyValues(alert.Labels),
+			formatKeyValues(alert.Annotations),
+			alert.StartsAt,
+			alert.EndsAt,
+			alert.Fingerprint,
+		})
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func renderAlertDetailsMarkdown(alerts []alertmanager.Alert) []byte {
+	var builder strings.Builder
+
+	builder.WriteString("| Status | Labels | Annotations | Starts At | Ends At | Fingerprint |\n")
+	builder.WriteString("|---|---|---|---|---|---|\n")
+
+	escape := strings.NewReplacer("|", "\\|", "\n", "<br>")
+	for _, alert := range alerts {
+		builder.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n",
+			alert.Status,
+			escape.Replace(formatKeyValues(alert.Labels)),
+			escape.Replace(formatKeyValues(alert.Annotations)),
+			alert.StartsAt,
+			alert.EndsAt,
+			alert.Fingerprint))
+	}
+
+	return []byte(builder.String())
+}
+
+// formatKeyValues renders a label set as `key="value"` pairs, ordered by key
+func formatKeyValues(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", key, values[key]))
+	}
+
+	return strings.Join(pairs, ", ")
+}
@@ -0,0 +1,128 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEmbedSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		embed MessageEmbed
+		want  int
+	}{
+		{"empty", MessageEmbed{}, 0},
+		{"title and description", MessageEmbed{Title: "abc", Description: "de"}, 5},
+		{"runes", MessageEmbed{Title: "🔥é", Description: "…"}, 3},
+		{
+			"fields",
+			MessageEmbed{Title: "a", Fields: []EmbedField{{Name: "bc", Value: "def"}, {Name: "g", Value: "🔥"}}},
+			8,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := embedSize(test.embed); got != test.want {
+				t.Errorf("embedSize() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFitEmbeds(t *testing.T) {
+	embedOfSize := func(size int) MessageEmbed {
+		return MessageEmbed{Description: strings.Repeat("x", size)}
+	}
+	embedsOfSize := func(count, size int) []MessageEmbed {
+		embeds := make([]MessageEmbed, count)
+		for i := range embeds {
+			embeds[i] = embedOfSize(size)
+		}
+		return embeds
+	}
+
+	tests := []struct {
+		name    string
+		message WebhookParams
+		want    int
+	}{
+		{"fits", WebhookParams{Embeds: embedsOfSize(3, 100)}, 3},
+		{"leaves room for the summary embed", WebhookParams{Embeds: embedsOfSize(12, 10)}, 9},
+		{"total size", WebhookParams{Embeds: embedsOfSize(5, 1500)}, 3},
+		{"content counts", WebhookParams{Content: strings.Repeat("c", 1500), Embeds: embedsOfSize(5, 1500)}, 2},
+		{
+			"fields count",
+			WebhookParams{Embeds: []MessageEmbed{
+				{Fields: []EmbedField{{Name: "n", Value: strings.Repeat("v", 1023)}}},
+				{Fields: []EmbedField{{Name: "n", Value: strings.Repeat("v", 1023)}}},
+				{Fields: []EmbedField{{Name: "n", Value: strings.Repeat("v", 1023)}}},
+				{Fields: []EmbedField{{Name: "n", Value: strings.Repeat("v", 1023)}}},
+				{Fields: []EmbedField{{Name: "n", Value: strings.Repeat("v", 1023)}}},
+				{Fields: []EmbedField{{Name: "n", Value: strings.Repeat("v", 1023)}}},
+			}},
+			5,
+		},
+		{"measured in runes", WebhookParams{Embeds: []MessageEmbed{{Description: strings.Repeat("🔥", 4000)}}}, 1},
+		{"long description is truncated", WebhookParams{Embeds: []MessageEmbed{embedOfSize(5000)}}, 1},
+		{"long content is truncated", WebhookParams{Content: strings.Repeat("[link](url)\n", 300), Embeds: embedsOfSize(3, 100)}, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			message := test.message
+			got := fitEmbeds(&message)
+			if got != test.want || len(message.Embeds) != test.want {
+				t.Fatalf("fitEmbeds() = %d with %d embeds, want %d", got, len(message.Embeds), test.want)
+			}
+
+			total := utf8.RuneCountInString(message.Content)
+			if total > maxContentSize {
+				t.Errorf("Content of %d characters kept", total)
+			}
+			for _, embed := range message.Embeds {
+				if size := utf8.RuneCountInString(embed.Description); size > maxEmbedDescriptionSize {
+					t.Errorf("Description of %d characters kept", size)
+				}
+				total += embedSize(embed)
+			}
+			if total > maxEmbedsTotalSize {
+				t.Errorf("Fitted message has %d characters", total)
+			}
+		})
+	}
+}
+
+func TestMessageOverflows(t *testing.T) {
+	tests := []struct {
+		name    string
+		message WebhookParams
+		want    bool
+	}{
+		{"fits", WebhookParams{Content: strings.Repeat("c", maxContentSize), Embeds: make([]MessageEmbed, 10)}, false},
+		{"too many embeds", WebhookParams{Embeds: make([]MessageEmbed, 11)}, true},
+		{"content too long", WebhookParams{Content: strings.Repeat("c", maxContentSize+1)}, true},
+		{"description too long", WebhookParams{Embeds: []MessageEmbed{{Description: strings.Repeat("d", 4097)}}}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := messageOverflows(&test.message); got != test.want {
+				t.Errorf("messageOverflows() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTruncateContent(t *testing.T) {
+	content := strings.Repeat("[link](https://example.com)\n", 100)
+
+	got := truncateContent(content, 200)
+	if size := utf8.RuneCountInString(got); size > 200 {
+		t.Errorf("Content of %d characters", size)
+	}
+	if !strings.HasSuffix(got, "(https://example.com)\n…") {
+		t.Errorf("Expected the content cut after a whole line, got %q", got)
+	}
+}
@@ -2,77 +2,335 @@ package discord
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/kolesaev/alertmanager-discord/alertmanager"
 	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/filter"
+	"github.com/kolesaev/alertmanager-discord/health"
+	"github.com/kolesaev/alertmanager-discord/history"
+	"github.com/kolesaev/alertmanager-discord/logging"
+	"github.com/kolesaev/alertmanager-discord/metrics"
+	"github.com/kolesaev/alertmanager-discord/oncall"
+	"github.com/kolesaev/alertmanager-discord/redact"
+	"github.com/kolesaev/alertmanager-discord/tracing"
+	"github.com/kolesaev/alertmanager-discord/windows"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// SendAlerts deals with the macro logic of sending alerts to Discord Channels
+// SendAlerts deals with the macro logic of sending alerts to Discord Channels.
+// Logs are written with the logger carried by ctx, with the channel added.
+// The result is set once the message is posted, even if Discord rejects it
 func SendAlerts(
+	ctx context.Context,
 	discordChannelName string,
 	alertmanagerBody alertmanager.MessageBody,
-	configs config.Config) error {
+	configs config.Config) (result DeliveryResult, err error) {
 
-	alertmanagerBodyInfo := alertmanager.ExtractBodyInfo(alertmanagerBody, configs)
+	ctx, span := tracing.Start(ctx, "discord.SendAlerts",
+		trace.WithAttributes(attribute.String("discord.channel", discordChannelName)))
+	defer func() { tracing.End(span, err) }()
 
-	discordChannel, err := getDiscordChannel(discordChannelName, configs)
+	logger := logging.FromContext(ctx).With("channel", discordChannelName)
+	ctx = logging.WithLogger(ctx, logger)
+
+	historyEntry := newHistoryEntry(ctx, discordChannelName, alertmanagerBody, configs)
+	defer func() { recordHistory(ctx, historyEntry, result, err) }()
+
+	preview, err := PreviewAlerts(ctx, discordChannelName, alertmanagerBody, configs)
 	if err != nil {
-		return fmt.Errorf("discord.SendAlerts: Error trying to get Discord Channel \n%+v", err)
+		return result, fmt.Errorf("discord.SendAlerts: Error trying to create Discord Message \n%+v", err)
 	}
 
-	if alertmanager.CheckIfHasOnlySeveritiesToIgnoreWhenAlone(
-		alertmanagerBodyInfo.CountBySeverity,
-		discordChannel, configs) {
+	if preview.TruncatedCount > 0 {
+		metrics.TruncatedNotifications.WithLabelValues(discordChannelName).Inc()
+		metrics.TruncatedAlerts.WithLabelValues(discordChannelName).Add(float64(preview.TruncatedCount))
+	}
+
+	if preview.FilteredCount > 0 {
+		metrics.SuppressedAlerts.WithLabelValues(discordChannelName, SuppressedByFilter).
+			Add(float64(preview.FilteredCount))
+	}
+	if preview.DroppedResolvedCount > 0 {
+		metrics.SuppressedAlerts.WithLabelValues(discordChannelName, SuppressedByResolvedPolicy).
+			Add(float64(preview.DroppedResolvedCount))
+	}
 
-		return fmt.Errorf(
-			`discord.SendAlerts: There are only alerts with severities to be ignored, message not sent.
+	if preview.SuppressionReason != "" {
+		metrics.SuppressedNotifications.WithLabelValues(discordChannelName, preview.SuppressedBy).Inc()
+		historyEntry.Outcome = history.OutcomeSuppressed
+		historyEntry.SuppressionReason = preview.SuppressionReason
+
+		return result, fmt.Errorf(
+			`discord.SendAlerts: Message not sent, %s.
 			Severity Count: %+v`,
-			alertmanagerBodyInfo.CountBySeverity)
+			preview.SuppressionReason, preview.CountBySeverity)
+	}
+
+	startedAt := time.Now()
+	result, err = sendMessage(ctx, discordChannelName, preview.Message, preview.Files, configs, &historyEntry)
+
+	if err == nil {
+		logger.Info("Delivered alerts to Discord",
+			"alertCount", preview.AlertCount,
+			"embedCount", len(preview.Message.Embeds),
+			"messageID", result.MessageID,
+			"duration", time.Since(startedAt))
+	}
+
+	return result, err
+}
+
+// sendMessage posts a rendered message to the channel's webhook and records
+// the delivery in the channel's health. The JSON message is set in
+// historyEntry, when given
+func sendMessage(
+	ctx context.Context,
+	discordChannelName string,
+	discordMessage WebhookParams,
+	files []File,
+	configs config.Config,
+	historyEntry *history.Entry) (DeliveryResult, error) {
 
+	jsonDiscordMessage, err := json.Marshal(discordMessage)
+	if err != nil {
+		return DeliveryResult{}, fmt.Errorf("discord.sendMessage: Error Marshaling Discord Message \n%+v", err)
+	}
+	if historyEntry != nil {
+		historyEntry.Message = jsonDiscordMessage
 	}
 
-	discordMessage, err := createDiscordMessage(alertmanagerBodyInfo, discordChannel, configs)
+	requestBody, contentType, err := encodeMessage(jsonDiscordMessage, files)
 	if err != nil {
-		return fmt.Errorf("discord.SendAlerts: Error trying to create Discord Message \n%+v", err)
+		return DeliveryResult{}, fmt.Errorf("discord.sendMessage: Error encoding Discord Message \n%+v", err)
 	}
 
-	jsonDiscordMessage, err := json.Marshal(discordMessage)
+	webhookURL, err := configs.DiscordChannels[discordChannelName].GetWebhookURL()
 	if err != nil {
-		return fmt.Errorf("discord.SendAlerts: Error Marshaling Discord Message \n%+v", err)
+		err = fmt.Errorf("discord.sendMessage: Error trying to get the webhook URL \n%+v", err)
+		recordDelivery(discordChannelName, err)
+		return DeliveryResult{}, err
 	}
 
-	r, err := http.Post(
-		discordChannel.WebhookURL,
-		"application/json",
-		bytes.NewReader(jsonDiscordMessage))
+	result, err := postMessage(ctx, webhookURL, contentType, requestBody)
+	recordDelivery(discordChannelName, err)
+
+	return result, err
+}
 
+// recordDelivery records the delivery in the health of the channel, which is
+// served by the status API, so the error is redacted
+func recordDelivery(discordChannelName string, err error) {
 	if err != nil {
-		return fmt.Errorf("discord.SendAlerts: Error Posting alert to Discord \n%+v", err)
+		err = errors.New(redact.Error(err))
 	}
+	health.Default.RecordDelivery(discordChannelName, err)
+}
 
-	defer r.Body.Close()
+// What a notification can be suppressed by
+const (
+	SuppressedByIgnoreWhenAlone = "ignoreWhenAlone"
+	SuppressedByWindow          = "window"
+	SuppressedByResolvedPolicy  = "resolvedPolicy"
+	SuppressedByFilter          = "filter"
+)
 
-	if r.StatusCode != 204 {
-		contents, err := ioutil.ReadAll(r.Body)
-		if err != nil {
-			log.Println("discord.SendAlerts: Error reading response body", err)
+// Preview is the message rendered for a notification
+type Preview struct {
+	Message WebhookParams
+	Files   []File
+	// SuppressionReason tells why no message would be sent, if so, and
+	// SuppressedBy what suppressed it, e.g. SuppressedByWindow
+	SuppressionReason string
+	SuppressedBy      string
+	// Window is the active time window with the strongest effect on the
+	// message, if any
+	Window         *windows.Window
+	AlertCount     int
+	TruncatedCount int
+	// FilteredCount is the number of alerts dropped by the channel's include
+	// and exclude filters, and DroppedResolvedCount the number of resolved
+	// alerts dropped by its resolved policy
+	FilteredCount        int
+	DroppedResolvedCount int
+	CountBySeverity      map[string]int
+}
+
+// PreviewAlerts renders the message for the notification as SendAlerts does,
+// without sending it
+func PreviewAlerts(
+	ctx context.Context,
+	discordChannelName string,
+	alertmanagerBody alertmanager.MessageBody,
+	configs config.Config) (Preview, error) {
+
+	discordChannel, err := getDiscordChannel(discordChannelName, configs)
+	if err != nil {
+		return Preview{}, fmt.Errorf("discord.PreviewAlerts: Error trying to get Discord Channel \n%+v", err)
+	}
+
+	channelFilters, err := filter.Default.Get(discordChannelName, configs)
+	if err != nil {
+		return Preview{}, fmt.Errorf("discord.PreviewAlerts: Error trying to get the filters \n%+v", err)
+	}
+
+	preview := Preview{
+		AlertCount:     len(alertmanagerBody.Alerts),
+		TruncatedCount: int(alertmanagerBody.TruncatedAlerts),
+	}
+
+	receivedAlerts := alertmanagerBody.Alerts
+	alertmanagerBody.Alerts, preview.FilteredCount = channelFilters.Apply(receivedAlerts)
+	if len(receivedAlerts) > 0 && len(alertmanagerBody.Alerts) == 0 {
+		preview.SuppressionReason = "the channel's filters drop all the alerts"
+		preview.SuppressedBy = SuppressedByFilter
+		return preview, nil
+	}
+
+	alertmanagerBodyInfo := alertmanager.ExtractBodyInfo(
+		ctx, alertmanagerBody, getGroupBy(discordChannel, configs), configs)
+
+	preview.DroppedResolvedCount = alertmanager.ApplyResolvedPolicy(
+		&alertmanagerBodyInfo, discordChannel.Resolved)
+	preview.CountBySeverity = alertmanagerBodyInfo.CountBySeverity
+
+	if preview.DroppedResolvedCount > 0 &&
+		alertmanagerBodyInfo.FiringCount == 0 && alertmanagerBodyInfo.ResolvedCount == 0 {
+
+		preview.SuppressionReason = "the channel's resolved policy drops all the alerts"
+		preview.SuppressedBy = SuppressedByResolvedPolicy
+		return preview, nil
+	}
+
+	// The collapsed resolved alerts are still shown, so they aren't alone
+	if alertmanagerBodyInfo.CollapsedResolvedCount == 0 &&
+		channelFilters.DropWhenAlone(collectAlerts(alertmanagerBodyInfo)) {
+
+		preview.SuppressionReason = "there are only alerts to ignore when alone"
+		preview.SuppressedBy = SuppressedByIgnoreWhenAlone
+		return preview, nil
+	}
+
+	preview.Window = windows.Strongest(
+		windows.Default.Active(discordChannelName, alertmanagerBodyInfo.CountBySeverity, time.Now()))
+	if preview.Window != nil {
+		logging.FromContext(ctx).Debug("Time window active",
+			"window", preview.Window.Name, "action", preview.Window.Action)
+		trace.SpanFromContext(ctx).SetAttributes(
+			attribute.String("discord.window", preview.Window.Name),
+			attribute.String("discord.window.action", preview.Window.Action))
+
+		if preview.Window.Action == windows.ActionSuppress {
+			preview.SuppressionReason = preview.Window.Describe()
+			preview.SuppressedBy = SuppressedByWindow
+			return preview, nil
 		}
+	}
+
+	preview.Message, preview.Files, err = createDiscordMessage(
+		ctx, alertmanagerBodyInfo, discordChannel, preview.Window, configs)
+	if err != nil {
+		return Preview{}, fmt.Errorf("discord.PreviewAlerts: Error trying to create Discord Message \n%+v", err)
+	}
+
+	return preview, nil
+}
+
+// webhookTimeout bounds a post to Discord, including the upload of the files
+const webhookTimeout = 30 * time.Second
+
+// webhookClient posts the messages to the webhooks
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// postMessage posts the encoded message to the webhook. Errors never contain
+// the webhook token, nor the message, which holds the alerts
+func postMessage(
+	ctx context.Context,
+	webhookURL, contentType string,
+	requestBody []byte) (result DeliveryResult, err error) {
+
+	ctx, span := tracing.Start(ctx, "discord.postMessage",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.Int("http.request.body.size", len(requestBody))))
+	defer func() { tracing.End(span, err) }()
+
+	logger := logging.FromContext(ctx)
+
+	postURL, err := withWait(webhookURL)
+	if err != nil {
+		return result, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, postURL, bytes.NewReader(requestBody))
+	if err != nil {
+		return result, fmt.Errorf("discord.postMessage: Error creating the request \n%s", redact.Error(err))
+	}
+	request.Header.Set("Content-Type", contentType)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	r, err := webhookClient.Do(request)
+	if err != nil {
+		return result, fmt.Errorf("discord.postMessage: Error Posting alert to Discord \n%s", redact.Error(err))
+	}
+
+	defer r.Body.Close()
+
+	logger.Debug("Discord responded", "discordStatus", r.StatusCode)
+	span.SetAttributes(attribute.Int("http.response.status_code", r.StatusCode))
+
+	result.StatusCode = r.StatusCode
 
-		return fmt.Errorf(
-			`discord.SendAlerts: Problem with Post, status code is not 204.
-			StatusCode: %d, Message: %s, Request Body: %s, Response Body: %s`,
-			r.StatusCode, r.Status, string(jsonDiscordMessage), string(contents))
+	contents, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		logger.Warn("Error reading Discord response body", "discordStatus", r.StatusCode, "error", err)
+	}
+	if json.Valid(contents) {
+		result.Response = contents
+	}
+
+	if r.StatusCode != 204 && r.StatusCode != 200 {
+		return result, fmt.Errorf(
+			`discord.postMessage: Problem with Post, status code is not 204 or 200.
+			StatusCode: %d, Message: %s, Response Body: %s`,
+			r.StatusCode, r.Status, string(contents))
+	}
+
+	var message struct {
+		ID string `json:"id"`
+	}
+	if len(result.Response) > 0 && json.Unmarshal(result.Response, &message) == nil {
+		result.MessageID = message.ID
 	}
 
-	return nil
+	return result, nil
+}
+
+// withWait adds wait=true to the webhook URL, so Discord answers with the
+// created message instead of an empty 204
+func withWait(webhookURL string) (string, error) {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", fmt.Errorf("discord.withWait: Invalid webhook URL \n%s", redact.Error(err))
+	}
+
+	query := parsed.Query()
+	query.Set("wait", "true")
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String(), nil
 }
 
 func getDiscordChannel(
@@ -90,103 +348,157 @@ func getDiscordChannel(
 	return config.DiscordChannel{}, err
 }
 
+// getGroupBy returns the labels used to group alerts in embeds. Channels can
+// override the global groupBy
+func getGroupBy(discordChannel config.DiscordChannel, configs config.Config) []string {
+	if len(discordChannel.GroupBy) > 0 {
+		return discordChannel.GroupBy
+	}
+	return configs.GroupBy
+}
+
 func createDiscordMessage(
+	ctx context.Context,
 	alertmanagerBodyInfo alertmanager.MessageBodyInfo,
 	discordChannel config.DiscordChannel,
-	configs config.Config) (message WebhookParams, err error) {
+	window *windows.Window,
+	configs config.Config) (message WebhookParams, files []File, err error) {
+
+	ctx, span := tracing.Start(ctx, "discord.createDiscordMessage")
+	defer func() {
+		span.SetAttributes(
+			attribute.Int("discord.embeds", len(message.Embeds)),
+			attribute.Int("discord.files", len(files)))
+		tracing.End(span, err)
+	}()
 
 	var contentBuilder strings.Builder
 
-	handleMentions(alertmanagerBodyInfo, &contentBuilder, discordChannel, configs)
+	handleMentions(ctx, alertmanagerBodyInfo, &contentBuilder, discordChannel, window, configs)
 
-	var dashboardURL string
-	if configs.DashboardLink.Enabled {
-		dashboardURL = getDashboardURLFromGroup(alertmanagerBodyInfo, configs)
-	}
+	linkDefinitions := parseLinkDefinitions(ctx, configs)
 
-	var generatorURL string
-	if configs.GeneratorLink.Enabled {
-		generatorURL = getGeneratorURLFromAlerts(alertmanagerBodyInfo)
-	}
+	firingLinks := resolveGroupLinks(ctx,
+		alertmanagerBodyInfo.FiringAlertsGroupedByName, linkDefinitions, alertmanagerBodyInfo, configs)
+	resolvedLinks := resolveGroupLinks(ctx,
+		alertmanagerBodyInfo.ResolvedAlertsGroupedByName, linkDefinitions, alertmanagerBodyInfo, configs)
 
-	if configs.DashboardLink.Enabled && configs.DashboardLink.Position == "content" && dashboardURL != "" {
-		contentBuilder.WriteString(fmt.Sprintf("[%s](%s)", configs.DashboardLink.Text, dashboardURL))
-	}
+	// The notices follow the content links, which get the room left
+	var noticesBuilder strings.Builder
 
-	if configs.GeneratorLink.Enabled && configs.GeneratorLink.Position == "content" && generatorURL != "" {
-		if configs.DashboardLink.Enabled && configs.DashboardLink.Position == "content" && dashboardURL != "" {
-			contentBuilder.WriteString("\n")
-		}
-		contentBuilder.WriteString(fmt.Sprintf("[%s](%s)", configs.GeneratorLink.Text, generatorURL))
+	addTruncatedAlertsNotice(alertmanagerBodyInfo, &noticesBuilder)
+
+	addCollapsedResolvedNotice(alertmanagerBodyInfo, &noticesBuilder, configs)
+
+	writeContentLinks(&contentBuilder, linkDefinitions, []map[string]groupLinks{firingLinks, resolvedLinks},
+		maxContentSize-utf8.RuneCountInString(noticesBuilder.String())-1)
+
+	if noticesBuilder.Len() > 0 {
+		writeContentLine(&contentBuilder, noticesBuilder.String())
 	}
 
-	firingEmbeds, err := createDiscordMessageEmbeds(alertmanagerBodyInfo.FiringAlertsGroupedByName,
-		"firing", configs, dashboardURL, generatorURL)
+	panelRenderer := newPanelRenderer(ctx, configs)
+
+	firingEmbedQueue, err := createEmbedQueue(ctx, alertmanagerBodyInfo.FiringAlertsGroupedByName,
+		"firing", configs, firingLinks, panelRenderer)
 	if err != nil {
 		err = fmt.Errorf("discord.createDiscordMessage: Error creating firingEmbeds\n%+v", err)
-		return WebhookParams{}, err
+		return WebhookParams{}, nil, err
 	}
 
-	resolvedEmbeds, err := createDiscordMessageEmbeds(alertmanagerBodyInfo.ResolvedAlertsGroupedByName,
-		"resolved", configs, dashboardURL, generatorURL)
+	resolvedEmbedQueue, err := createEmbedQueue(ctx, alertmanagerBodyInfo.ResolvedAlertsGroupedByName,
+		"resolved", configs, resolvedLinks, panelRenderer)
 	if err != nil {
 		err = fmt.Errorf("discord.createDiscordMessage: Error creating resolvedEmbeds %+v", err)
-		return WebhookParams{}, err
+		return WebhookParams{}, nil, err
 	}
 
-	embeds := append(firingEmbeds, resolvedEmbeds...)
+	embeds := orderEmbeds(firingEmbedQueue, resolvedEmbedQueue, configs)
 
-	return WebhookParams{
+	message = WebhookParams{
 		Content:   contentBuilder.String(),
 		Embeds:    embeds,
 		Username:  configs.Username,
-		AvatarURL: configs.AvatarURL}, nil
-}
+		AvatarURL: configs.AvatarURL}
 
-func getDashboardURLFromGroup(alertmanagerBodyInfo alertmanager.MessageBodyInfo, configs config.Config) string {
-	labelName := configs.DashboardLink.Label
+	detailsFile, err := attachAlertDetails(&message, alertmanagerBodyInfo, configs)
+	if err != nil {
+		err = fmt.Errorf("discord.createDiscordMessage: Error attaching alert details \n%+v", err)
+		return WebhookParams{}, nil, err
+	}
 
-	if url, ok := alertmanagerBodyInfo.GroupLabels[labelName]; ok && url != "" {
-		return url
+	maxPanels := maxFilesPerMessage
+	if detailsFile != nil {
+		maxPanels--
+	}
+	files = panelRenderer.renderPanelImages(ctx, message.Embeds, maxPanels)
+	if detailsFile != nil {
+		files = append(files, *detailsFile)
 	}
 
-	if url, ok := alertmanagerBodyInfo.CommonLabels[labelName]; ok && url != "" {
-		return url
+	return message, files, nil
+}
+
+// addTruncatedAlertsNotice tells how many alerts Alertmanager dropped from the
+// notification and links to its UI, where the full list can be found
+func addTruncatedAlertsNotice(
+	alertmanagerBodyInfo alertmanager.MessageBodyInfo,
+	contentBuilder *strings.Builder) {
+
+	if alertmanagerBodyInfo.TruncatedCount <= 0 {
+		return
 	}
 
-	if url, ok := alertmanagerBodyInfo.CommonAnnotations[labelName]; ok && url != "" {
-		return url
+	if contentBuilder.Len() > 0 {
+		contentBuilder.WriteString("\n")
 	}
 
-	return ""
+	contentBuilder.WriteString(fmt.Sprintf(
+		":warning: %d more alerts were truncated by Alertmanager and are not shown.",
+		alertmanagerBodyInfo.TruncatedCount))
+
+	alertsURL := alertmanager.GetAlertsURL(
+		alertmanagerBodyInfo.ExternalURL,
+		alertmanagerBodyInfo.GroupLabels,
+		alertmanagerBodyInfo.Receiver)
+	if alertsURL != "" {
+		contentBuilder.WriteString(fmt.Sprintf(" [See all alerts](%s)", alertsURL))
+	}
 }
 
-func getGeneratorURLFromAlerts(alertmanagerBodyInfo alertmanager.MessageBodyInfo) string {
-	// Check firing alerts first
-	for _, groupData := range alertmanagerBodyInfo.FiringAlertsGroupedByName {
-		for _, alert := range groupData.Alerts {
-			if alert.GeneratorURL != "" {
-				return alert.GeneratorURL
-			}
-		}
+// addCollapsedResolvedNotice tells how many alerts resolved, when the
+// channel's resolved policy collapses them
+func addCollapsedResolvedNotice(
+	alertmanagerBodyInfo alertmanager.MessageBodyInfo,
+	contentBuilder *strings.Builder,
+	configs config.Config) {
+
+	if alertmanagerBodyInfo.CollapsedResolvedCount <= 0 {
+		return
 	}
 
-	// If not found in firing, check resolved alerts
-	for _, groupData := range alertmanagerBodyInfo.ResolvedAlertsGroupedByName {
-		for _, alert := range groupData.Alerts {
-			if alert.GeneratorURL != "" {
-				return alert.GeneratorURL
-			}
-		}
+	if contentBuilder.Len() > 0 {
+		contentBuilder.WriteString("\n")
+	}
+
+	alerts := "alerts"
+	if alertmanagerBodyInfo.CollapsedResolvedCount == 1 {
+		alerts = "alert"
 	}
 
-	return ""
+	contentBuilder.WriteString(fmt.Sprintf("%s %d %s resolved",
+		configs.Status["resolved"].Emoji, alertmanagerBodyInfo.CollapsedResolvedCount, alerts))
 }
 
+// handleMentions mentions the roles, or the user on call, when the severities
+// or the number of firing alerts call for it. An active time window can strip
+// the mentions or replace them with its roles
 func handleMentions(
+	ctx context.Context,
 	alertmanagerBodyInfo alertmanager.MessageBodyInfo,
 	contentBuilder *strings.Builder,
 	discordChannel config.DiscordChannel,
+	window *windows.Window,
 	configs config.Config) {
 
 	var severitiesToMention []string
@@ -201,10 +513,27 @@ func handleMentions(
 	shouldMentionBySeverity := checkIfShouldMentionBySeverity(severitiesToMention, alertmanagerBodyInfo, configs)
 	shouldMentionByFiringCount := checkIfShouldMentionByFiringCount(alertmanagerBodyInfo, configs)
 
-	if shouldMentionBySeverity || shouldMentionByFiringCount {
-		addRolesToEmbedContent(contentBuilder, discordChannel, configs)
+	if !shouldMentionBySeverity && !shouldMentionByFiringCount {
+		return
+	}
+
+	if window != nil {
+		switch window.Action {
+		case windows.ActionStripMentions:
+			return
+		case windows.ActionDowngradeMentions:
+			if len(window.RolesToMention) > 0 {
+				contentBuilder.WriteString("    " + strings.Join(window.RolesToMention, " "))
+			}
+			return
+		}
+	}
+
+	if discordChannel.Rotation != "" && addOnCallToEmbedContent(ctx, contentBuilder, discordChannel.Rotation) {
+		return
 	}
 
+	addRolesToEmbedContent(contentBuilder, discordChannel, configs)
 }
 
 func checkIfShouldMentionBySeverity(
@@ -226,7 +555,9 @@ func checkIfShouldMentionByFiringCount(
 	configs config.Config) bool {
 
 	if configs.FiringCountToMention > 0 {
-		if alertmanagerBodyInfo.FiringCount >= configs.FiringCountToMention {
+		// Truncated alerts are counted as firing, since they can't be told apart
+		firingCount := alertmanagerBodyInfo.FiringCount + alertmanagerBodyInfo.TruncatedCount
+		if firingCount >= configs.FiringCountToMention {
 			return true
 		}
 	}
@@ -249,23 +580,79 @@ func addRolesToEmbedContent(
 
 }
 
-func createDiscordMessageEmbeds(
+// addOnCallToEmbedContent mentions the user currently on call in the
+// rotation. It tells whether someone was mentioned, so the roles are
+// mentioned instead when nobody is on call or the schedule can't be read
+func addOnCallToEmbedContent(ctx context.Context, contentBuilder *strings.Builder, rotation string) bool {
+	logger := logging.FromContext(ctx)
+
+	userID, err := oncall.Default.OnCall(rotation, time.Now())
+	if err != nil {
+		logger.Error("Mentioning the roles instead of the user on call", "rotation", rotation, "error", err)
+		return false
+	}
+	if userID == "" {
+		logger.Warn("Nobody on call, mentioning the roles instead", "rotation", rotation)
+		return false
+	}
+
+	logger.Debug("Mentioning the user on call", "rotation", rotation, "userID", userID)
+	contentBuilder.WriteString(fmt.Sprintf("    <@%s>", userID))
+	return true
+}
+
+// orderEmbeds sorts the embeds by the configured keys, putting firing embeds
+// before resolved ones unless they should be interleaved
+func orderEmbeds(firingEmbedQueue, resolvedEmbedQueue []EmbedQueueItem, configs config.Config) []MessageEmbed {
+	var embedQueue []EmbedQueueItem
+
+	if configs.Sort.InterleaveStatus {
+		embedQueue = append(firingEmbedQueue, resolvedEmbedQueue...)
+		sortEmbedQueue(embedQueue, configs.Sort.Groups)
+	} else {
+		sortEmbedQueue(firingEmbedQueue, configs.Sort.Groups)
+		sortEmbedQueue(resolvedEmbedQueue, configs.Sort.Groups)
+		embedQueue = append(firingEmbedQueue, resolvedEmbedQueue...)
+	}
+
+	embeds := []MessageEmbed{}
+
+	for _, embedQueueItem := range embedQueue {
+		embeds = append(embeds, embedQueueItem.Embed)
+	}
+
+	return embeds
+}
+
+func createEmbedQueue(
+	ctx context.Context,
 	alertsGroupedByName alertmanager.AlertsGroupedByLabel,
 	status string,
 	configs config.Config,
-	dashboardURL string,
-	generatorURL string) ([]MessageEmbed, error) {
+	linksByGroup map[string]groupLinks,
+	panelRenderer *panelRenderer) ([]EmbedQueueItem, error) {
 
 	embedQueue := []EmbedQueueItem{}
 
-	for _, groupData := range alertsGroupedByName {
+	groupKeys := make([]string, 0, len(alertsGroupedByName))
+	for groupKey := range alertsGroupedByName {
+		groupKeys = append(groupKeys, groupKey)
+	}
+	sort.Strings(groupKeys)
+
+	for _, groupKey := range groupKeys {
+		groupData := alertsGroupedByName[groupKey]
+		groupData.Alerts = sortAlerts(groupData.Alerts, configs)
+
 		embed := MessageEmbed{}
 
 		// Get title using correct Telegram template logic
-		embed.Title = getAlertTitle(groupData.Alerts, groupData.GroupLabels)
+		title := getGroupTitle(groupData)
+
+		links := linksByGroup[groupKey]
 
 		description := ""
-		for _, alert := range groupData.Alerts {
+		for i, alert := range groupData.Alerts {
 			alertText := "```"
 
 			if configs.TimeDisplay.Enabled && !shouldHideTimeForSeverity(alert, configs) {
@@ -279,7 +666,7 @@ func createDiscordMessageEmbeds(
 			}
 
 			if configs.TimeDisplay.Enabled {
-				timeInfo := formatAlertTimeInfo(alert, status, configs)
+				timeInfo := formatAlertTimeInfo(ctx, alert, status, configs)
 				if timeInfo != "" {
 					alertText += "\n" + timeInfo
 				}
@@ -287,6 +674,8 @@ func createDiscordMessageEmbeds(
 
 			alertText += "```"
 
+			alertText += formatAlertLinks(links, i)
+
 			description += alertText
 		}
 
@@ -294,92 +683,78 @@ func createDiscordMessageEmbeds(
 			description = strings.TrimSuffix(description, "\n\n")
 		}
 
-		priority, err := handleEmbedAppearance(&embed, status, groupData.Alerts[0], configs)
+		highestSeverityAlert := getHighestSeverityAlert(groupData.Alerts, configs)
+
+		priority, err := handleEmbedAppearance(&embed, status, title, highestSeverityAlert, configs)
 		if err != nil {
 			err = fmt.Errorf(
-				`discord.createDiscordMessageEmbeds:
+				`discord.createEmbedQueue:
 				Couldn't handle embed appearance for embed %+v and alert %+v: \n%+v`,
-				embed, groupData.Alerts[0], err)
-			return []MessageEmbed{}, err
+				embed, highestSeverityAlert, err)
+			return []EmbedQueueItem{}, err
 		}
 
-		var linksBuilder strings.Builder
-		var dashboardInEmbed, generatorInEmbed bool
+		handleEmbedLinks(&embed, links, description)
 
-		if configs.DashboardLink.Enabled && dashboardURL != "" {
-			dashPos := configs.DashboardLink.Position
-			if dashPos == "embed_top" || dashPos == "embed_bottom" {
-				dashboardInEmbed = true
-			}
-		}
+		embed.Title = ""
 
-		if configs.GeneratorLink.Enabled && generatorURL != "" {
-			genPos := configs.GeneratorLink.Position
-			if genPos == "embed_top" || genPos == "embed_bottom" {
-				generatorInEmbed = true
-			}
-		}
+		panelRenderer.attachPanelImage(&embed, groupData.Alerts)
 
-		if dashboardInEmbed {
-			linksBuilder.WriteString(fmt.Sprintf("[%s](%s)", configs.DashboardLink.Text, dashboardURL))
-		}
-
-		if generatorInEmbed {
-			if linksBuilder.Len() > 0 {
-				linksBuilder.WriteString("\n")
-			}
-			linksBuilder.WriteString(fmt.Sprintf("[%s](%s)", configs.GeneratorLink.Text, generatorURL))
+		embedQueueItem := EmbedQueueItem{
+			Embed:    embed,
+			Priority: priority,
+			GroupKey: groupKey,
+			Title:    title,
+			Count:    len(groupData.Alerts),
+			StartsAt: getEarliestStartsAt(groupData.Alerts),
 		}
 
-		linksString := linksBuilder.String()
+		embedQueue = append(embedQueue, embedQueueItem)
 
-		dashPos := "none"
-		if configs.DashboardLink.Enabled && dashboardURL != "" {
-			dashPos = configs.DashboardLink.Position
-		}
+		logging.FromContext(ctx).Debug("Rendered embed",
+			"status", status, "groupKey", groupKey, "alertCount", len(groupData.Alerts))
+	}
 
-		genPos := "none"
-		if configs.GeneratorLink.Enabled && generatorURL != "" {
-			genPos = configs.GeneratorLink.Position
-		}
+	return embedQueue, nil
+}
 
-		linksPosition := "none"
-		if dashPos == "embed_top" || genPos == "embed_top" {
-			linksPosition = "top"
-		} else if dashPos == "embed_bottom" || genPos == "embed_bottom" {
-			linksPosition = "bottom"
-		}
+// getGroupTitle returns the title for a group's embed. Groups whose alerts
+// share an alertname keep the summary/alertname logic of getAlertTitle, other
+// groups are titled after their groupBy labels
+func getGroupTitle(groupData alertmanager.AlertGroup) string {
+	if _, ok := groupData.KeyLabels["alertname"]; ok || sameAlertName(groupData.Alerts) {
+		return getAlertTitle(groupData.Alerts, groupData.GroupLabels)
+	}
 
-		switch linksPosition {
-		case "top":
-			embed.Description = "### " + embed.Title + "\n" + linksString + "\n" + description
-		case "bottom":
-			embed.Description = "### " + embed.Title + "\n" + description + "\n" + linksString
-		default:
-			embed.Description = "### " + embed.Title + "\n" + description
-		}
+	if len(groupData.KeyLabels) == 0 {
+		return fmt.Sprintf("%d alerts", len(groupData.Alerts))
+	}
 
-		embed.Title = ""
+	labelNames := make([]string, 0, len(groupData.KeyLabels))
+	for labelName := range groupData.KeyLabels {
+		labelNames = append(labelNames, labelName)
+	}
+	sort.Strings(labelNames)
 
-		embedQueueItem := EmbedQueueItem{
-			Embed:    embed,
-			Priority: priority,
+	titleParts := make([]string, 0, len(labelNames))
+	for _, labelName := range labelNames {
+		labelValue := groupData.KeyLabels[labelName]
+		if labelValue == "" {
+			labelValue = "<none>"
 		}
-
-		embedQueue = append(embedQueue, embedQueueItem)
+		titleParts = append(titleParts, fmt.Sprintf("%s: %s", labelName, labelValue))
 	}
 
-	sort.Slice(embedQueue[:], func(i, j int) bool {
-		return embedQueue[i].Priority > embedQueue[j].Priority
-	})
-
-	embedsOrderedByPriority := []MessageEmbed{}
+	return strings.Join(titleParts, ", ")
+}
 
-	for _, embedQueueItem := range embedQueue {
-		embedsOrderedByPriority = append(embedsOrderedByPriority, embedQueueItem.Embed)
+func sameAlertName(alerts []alertmanager.Alert) bool {
+	for _, alert := range alerts[1:] {
+		if alert.Labels["alertname"] != alerts[0].Labels["alertname"] {
+			return false
+		}
 	}
-
-	return embedsOrderedByPriority, nil
+	return true
 }
 
 // getAlertTitle extracts title from group data following Telegram template logic
@@ -413,21 +788,22 @@ func getAlertTitle(alerts []alertmanager.Alert, groupLabels map[string]string) s
 
 func handleEmbedAppearance(
 	embed *MessageEmbed, status string,
+	title string,
 	alert alertmanager.Alert,
 	configs config.Config) (priority int, err error) {
 
 	if status == "resolved" {
 		embed.Color = configs.Status["resolved"].Color
-		embed.Title = fmt.Sprintf("%s %s", configs.Status["resolved"].Emoji, getAlertTitle([]alertmanager.Alert{alert}, alert.Labels))
+		embed.Title = fmt.Sprintf("%s %s", configs.Status["resolved"].Emoji, title)
 		return 0, nil
 	} else if status == "firing" {
 		switch configs.MessageType {
 		case "status":
 			embed.Color = configs.Status["firing"].Color
-			embed.Title = fmt.Sprintf("%s %s", configs.Status["firing"].Emoji, getAlertTitle([]alertmanager.Alert{alert}, alert.Labels))
+			embed.Title = fmt.Sprintf("%s %s", configs.Status["firing"].Emoji, title)
 			return 0, nil
 		case "severity":
-			severityAppearance := handleEmbedSeverity(embed, alert, configs)
+			severityAppearance := handleEmbedSeverity(embed, title, alert, configs)
 			return severityAppearance.Priority, nil
 		default:
 			return 0, fmt.Errorf(
@@ -439,21 +815,45 @@ func handleEmbedAppearance(
 	return 0, nil
 }
 
-func handleEmbedSeverity(embed *MessageEmbed, alert alertmanager.Alert, configs config.Config) config.SeverityAppearance {
-	severity, ok := alert.Labels[configs.Severity.Label]
-	var SeverityAppearance config.SeverityAppearance
-	if ok {
-		SeverityAppearance, ok = configs.Severity.Values[severity]
-		if !ok {
-			SeverityAppearance = configs.Severity.Values["unknown"]
+func handleEmbedSeverity(
+	embed *MessageEmbed,
+	title string,
+	alert alertmanager.Alert,
+	configs config.Config) config.SeverityAppearance {
+
+	SeverityAppearance := getSeverityAppearance(alert.Severity, configs)
+	embed.Title = fmt.Sprintf("%s %s", SeverityAppearance.Emoji, title)
+	embed.Color = SeverityAppearance.Color
+	return SeverityAppearance
+}
+
+func getSeverityAppearance(severity string, configs config.Config) config.SeverityAppearance {
+	severityAppearance, ok := configs.Severity.Values[severity]
+	if !ok {
+		severityAppearance = configs.Severity.Values["unknown"]
+	}
+	return severityAppearance
+}
+
+// getHighestSeverityAlert returns the alert with the highest severity
+// priority, which defines the appearance and priority of a group's embed.
+// Ties keep the first alert in the group
+func getHighestSeverityAlert(alerts []alertmanager.Alert, configs config.Config) alertmanager.Alert {
+	highest := alerts[0]
+	highestPriority := getSeverityAppearance(highest.Severity, configs).Priority
+
+	for _, alert := range alerts[1:] {
+		priority := getSeverityAppearance(alert.Severity, configs).Priority
+		if priority > highestPriority {
+			highest = alert
+			highestPriority = priority
 		}
-		embed.Title = fmt.Sprintf("%s %s", SeverityAppearance.Emoji, getAlertTitle([]alertmanager.Alert{alert}, alert.Labels))
-		embed.Color = SeverityAppearance.Color
 	}
-	return SeverityAppearance
+
+	return highest
 }
 
-func formatAlertTimeInfo(alert alertmanager.Alert, status string, configs config.Config) string {
+func formatAlertTimeInfo(ctx context.Context, alert alertmanager.Alert, status string, configs config.Config) string {
 	if !configs.TimeDisplay.Enabled {
 		return ""
 	}
@@ -467,7 +867,7 @@ func formatAlertTimeInfo(alert alertmanager.Alert, status string, configs config
 
 	startsAt, err := time.Parse(time.RFC3339, alert.StartsAt)
 	if err != nil {
-		log.Printf("ERROR: Failed to parse StartsAt time: %v", err)
+		logging.FromContext(ctx).Error("Failed to parse StartsAt time", "error", err)
 		return ""
 	}
 
@@ -478,7 +878,7 @@ func formatAlertTimeInfo(alert alertmanager.Alert, status string, configs config
 	if status == "resolved" && alert.EndsAt != "" {
 		endsAt, err := time.Parse(time.RFC3339, alert.EndsAt)
 		if err != nil {
-			log.Printf("ERROR: Failed to parse EndsAt time: %v", err)
+			logging.FromContext(ctx).Error("Failed to parse EndsAt time", "error", err)
 		} else {
 			localEndsAt := endsAt.Format("02.01.2006 15:04:05 MST")
 			duration := endsAt.Sub(startsAt)
@@ -500,14 +900,9 @@ func shouldHideTimeForSeverity(alert alertmanager.Alert, configs config.Config)
 		return false
 	}
 
-	severity, ok := alert.Labels[configs.Severity.Label]
-	if !ok {
-		return false
-	}
-
 	// Check if severity is in the hidden list
 	for _, hiddenSeverity := range configs.TimeDisplay.HiddenForSeverities {
-		if severity == hiddenSeverity {
+		if alert.Severity == hiddenSeverity {
 			return true
 		}
 	}
@@ -0,0 +1,234 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/health"
+)
+
+func TestPreviewKeepsCollapsedResolvedAlerts(t *testing.T) {
+	configs := loadTestConfig(t, `
+channels:
+  default:
+    name: default
+    webhookURL: https://discord.com/api/webhooks/1/token
+    severitiesToIgnoreWhenAlone: [info]
+    resolved:
+      send: collapsed
+`)
+
+	alert := func(status, name, severity string) alertmanager.Alert {
+		return alertmanager.Alert{
+			Status:   status,
+			Labels:   map[string]string{"alertname": name, "severity": severity},
+			StartsAt: "2024-01-01T00:00:00Z",
+		}
+	}
+
+	tests := []struct {
+		name       string
+		alerts     []alertmanager.Alert
+		wantNotice string
+	}{
+		{"only collapsed resolved alerts", []alertmanager.Alert{
+			alert("resolved", "HighLatency", "critical"),
+			alert("resolved", "HighErrorRate", "critical"),
+		}, "2 alerts resolved"},
+		{"info alerts with collapsed resolved ones", []alertmanager.Alert{
+			alert("firing", "DiskFilling", "info"),
+			alert("resolved", "HighLatency", "critical"),
+		}, "1 alert resolved"},
+		{"only info alerts", []alertmanager.Alert{
+			alert("firing", "DiskFilling", "info"),
+		}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := alertmanager.MessageBody{Status: "firing", Alerts: test.alerts}
+			preview, err := PreviewAlerts(context.Background(), "default", body, configs)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.wantNotice == "" {
+				if preview.SuppressedBy != SuppressedByIgnoreWhenAlone {
+					t.Errorf("Expected the message to be dropped, suppressed by %q", preview.SuppressedBy)
+				}
+				return
+			}
+			if preview.SuppressedBy != "" {
+				t.Fatalf("Expected the message to be sent, suppressed by %q", preview.SuppressedBy)
+			}
+			if !strings.Contains(preview.Message.Content, test.wantNotice) {
+				t.Errorf("Expected %q in the content, got %q", test.wantNotice, preview.Message.Content)
+			}
+		})
+	}
+}
+
+func TestPreviewKeepsContentWithinLimit(t *testing.T) {
+	configs := loadTestConfig(t, `
+generatorLink:
+  enabled: true
+channels:
+  default:
+    name: default
+    webhookURL: https://discord.com/api/webhooks/1/token
+`)
+
+	body := alertmanager.MessageBody{Status: "firing", TruncatedAlerts: 20}
+	for i := 0; i < 100; i++ {
+		body.Alerts = append(body.Alerts, alertmanager.Alert{
+			Status:       "firing",
+			Labels:       map[string]string{"alertname": fmt.Sprintf("Alert%03d", i), "severity": "critical"},
+			StartsAt:     "2024-01-01T00:00:00Z",
+			GeneratorURL: fmt.Sprintf("https://prometheus.example.com/graph?g0.expr=up%%7Bjob%%3D%%22job-%d%%22%%7D", i),
+		})
+	}
+
+	preview, err := PreviewAlerts(context.Background(), "default", body, configs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := preview.Message.Content
+	if size := utf8.RuneCountInString(content); size > maxContentSize {
+		t.Errorf("Content of %d characters", size)
+	}
+	if !strings.Contains(content, "more links") {
+		t.Errorf("Expected the links that don't fit to be counted, got %q", content)
+	}
+	if !strings.HasSuffix(content, "are not shown.") {
+		t.Errorf("Expected the truncated alerts notice after the links, got %q", content)
+	}
+}
+
+func TestFailedDeliveryErrorIsRedacted(t *testing.T) {
+	discordServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message": "Invalid Form Body", "code": 50035}`))
+	}))
+	defer discordServer.Close()
+
+	configs := loadTestConfig(t, `
+channels:
+  redacted:
+    name: redacted
+    webhookURL: `+discordServer.URL+`/api/webhooks/1/secret-token
+`)
+
+	body := alertmanager.MessageBody{Status: "firing", Alerts: []alertmanager.Alert{{
+		Status:      "firing",
+		Labels:      map[string]string{"alertname": "HighLatency", "severity": "critical"},
+		Annotations: map[string]string{"summary": "Customer data in the summary"},
+		StartsAt:    "2024-01-01T00:00:00Z",
+	}}}
+
+	_, err := SendAlerts(context.Background(), "redacted", body, configs)
+	if err == nil {
+		t.Fatal("Expected the delivery to fail")
+	}
+
+	for _, status := range health.Default.Channels() {
+		if status.Name != "redacted" {
+			continue
+		}
+		if !strings.Contains(status.LastError, "400") {
+			t.Errorf("Expected the status code in the last error, got %q", status.LastError)
+		}
+		for _, secret := range []string{"secret-token", "Customer data"} {
+			if strings.Contains(status.LastError, secret) || strings.Contains(err.Error(), secret) {
+				t.Errorf("Expected %q to be left out of the error, got %q", secret, status.LastError)
+			}
+		}
+		return
+	}
+	t.Errorf("Expected the delivery to be recorded")
+}
+
+func TestGetGroupTitle(t *testing.T) {
+	alert := func(alertname, summary string) alertmanager.Alert {
+		return alertmanager.Alert{
+			Labels:      map[string]string{"alertname": alertname},
+			Annotations: map[string]string{"summary": summary},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		group alertmanager.AlertGroup
+		want  string
+	}{
+		{"summary", alertmanager.AlertGroup{
+			Alerts:    []alertmanager.Alert{alert("HighLatency", "Latency is high")},
+			KeyLabels: map[string]string{"alertname": "HighLatency"},
+		}, "Latency is high"},
+		{"alertname", alertmanager.AlertGroup{
+			Alerts:    []alertmanager.Alert{alert("HighLatency", "")},
+			KeyLabels: map[string]string{"alertname": "HighLatency"},
+		}, "HighLatency"},
+		{"group label summary", alertmanager.AlertGroup{
+			Alerts:      []alertmanager.Alert{alert("HighLatency", "Latency is high")},
+			GroupLabels: map[string]string{"summary": "Platform alerts"},
+		}, "Platform alerts"},
+		{"same alertname without the label", alertmanager.AlertGroup{
+			Alerts:    []alertmanager.Alert{alert("HighLatency", ""), alert("HighLatency", "")},
+			KeyLabels: map[string]string{"namespace": "prod"},
+		}, "HighLatency"},
+		{"key labels", alertmanager.AlertGroup{
+			Alerts:    []alertmanager.Alert{alert("HighLatency", ""), alert("HighErrorRate", "")},
+			KeyLabels: map[string]string{"namespace": "prod", "cluster": ""},
+		}, "cluster: <none>, namespace: prod"},
+		{"none", alertmanager.AlertGroup{
+			Alerts:    []alertmanager.Alert{alert("HighLatency", ""), alert("HighErrorRate", "")},
+			KeyLabels: map[string]string{},
+		}, "2 alerts"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := getGroupTitle(test.group); got != test.want {
+				t.Errorf("getGroupTitle() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAddTruncatedAlertsNotice(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    alertmanager.MessageBodyInfo
+		content string
+		want    string
+	}{
+		{"not truncated", alertmanager.MessageBodyInfo{}, "links", "links"},
+		{"without external URL", alertmanager.MessageBodyInfo{TruncatedCount: 3}, "",
+			":warning: 3 more alerts were truncated by Alertmanager and are not shown."},
+		{"with external URL", alertmanager.MessageBodyInfo{
+			TruncatedCount: 3,
+			ExternalURL:    "http://alertmanager:9093",
+			GroupLabels:    map[string]string{"alertname": "HighLatency"},
+		}, "links",
+			"links\n:warning: 3 more alerts were truncated by Alertmanager and are not shown." +
+				" [See all alerts](http://alertmanager:9093/#/alerts?active=true&filter=%7Balertname%3D%22HighLatency%22%7D&inhibited=false&silenced=false)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var contentBuilder strings.Builder
+			contentBuilder.WriteString(test.content)
+			addTruncatedAlertsNotice(test.info, &contentBuilder)
+			if got := contentBuilder.String(); got != test.want {
+				t.Errorf("addTruncatedAlertsNotice() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package discord
+
+import (
+	"context"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/history"
+	"github.com/kolesaev/alertmanager-discord/logging"
+	"github.com/kolesaev/alertmanager-discord/redact"
+)
+
+// newHistoryEntry summarizes the received notification for the history
+func newHistoryEntry(
+	ctx context.Context,
+	discordChannelName string,
+	alertmanagerBody alertmanager.MessageBody,
+	configs config.Config) history.Entry {
+
+	alerts := make([]history.AlertSummary, len(alertmanagerBody.Alerts))
+	for i, alert := range alertmanagerBody.Alerts {
+		alerts[i] = history.AlertSummary{
+			Fingerprint: alert.Fingerprint,
+			AlertName:   alert.Labels["alertname"],
+			Status:      alert.Status,
+			Severity:    configs.Severity.Of(alert.Labels),
+		}
+	}
+
+	return history.Entry{
+		Time:            time.Now(),
+		RequestID:       logging.RequestID(ctx),
+		Channel:         discordChannelName,
+		Receiver:        alertmanagerBody.Receiver,
+		Status:          alertmanagerBody.Status,
+		GroupKey:        alertmanagerBody.GroupKey,
+		Alerts:          alerts,
+		TruncatedAlerts: int(alertmanagerBody.TruncatedAlerts),
+	}
+}
+
+// recordHistory completes the entry with the delivery's outcome and adds it
+// to the history. Suppressed entries already have their outcome set
+func recordHistory(ctx context.Context, entry history.Entry, result DeliveryResult, err error) {
+	entry.Duration = time.Since(entry.Time)
+	entry.DiscordStatus = result.StatusCode
+	entry.MessageID = result.MessageID
+
+	if entry.Outcome == "" {
+		entry.Outcome = history.OutcomeSent
+		if err != nil {
+			entry.Outcome = history.OutcomeFailed
+			entry.Error = redact.Error(err)
+		}
+	}
+
+	if err := history.Default.Add(entry); err != nil {
+		logging.FromContext(ctx).Error("Error recording the notification in the history", "error", err)
+	}
+}
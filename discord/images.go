@@ -0,0 +1,137 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/grafana"
+	"github.com/kolesaev/alertmanager-discord/logging"
+)
+
+// attachmentScheme prefixes the URLs referencing uploaded files
+const attachmentScheme = "attachment://"
+
+// maxFilesPerMessage is Discord's limit of files attached to a message
+const maxFilesPerMessage = 10
+
+// panelRenderer attaches Grafana panel images to embeds and renders the
+// files to be uploaded with the message. A nil client disables it.
+type panelRenderer struct {
+	client  *grafana.Client
+	configs config.GrafanaImageConfig
+	// panels are the requested panels, by file name
+	panels map[string]panel
+}
+
+// panel is a panel requested for an embed
+type panel struct {
+	dashboardUID string
+	panelID      string
+	startsAt     time.Time
+}
+
+func newPanelRenderer(ctx context.Context, configs config.Config) *panelRenderer {
+	renderer := &panelRenderer{configs: configs.GrafanaImage, panels: map[string]panel{}}
+
+	if !configs.GrafanaImage.Enabled {
+		return renderer
+	}
+
+	client, err := grafana.NewClient(configs.GrafanaImage)
+	if err != nil {
+		logging.FromContext(ctx).Error("Grafana images disabled", "error", err)
+		return renderer
+	}
+	renderer.client = client
+
+	return renderer
+}
+
+// attachPanelImage references the panel of the first alert carrying the
+// dashboard and panel annotations in the embed's image. It's only rendered
+// by renderPanelImages, once the embeds that fit in the message are known.
+func (p *panelRenderer) attachPanelImage(embed *MessageEmbed, alerts []alertmanager.Alert) {
+	if p.client == nil {
+		return
+	}
+
+	for _, alert := range alerts {
+		dashboardUID := alert.Annotations[p.configs.DashboardUIDAnnotation]
+		panelID := alert.Annotations[p.configs.PanelIDAnnotation]
+		if dashboardUID == "" || panelID == "" {
+			continue
+		}
+
+		startsAt, err := time.Parse(time.RFC3339, alert.StartsAt)
+		if err != nil {
+			startsAt = time.Now()
+		}
+
+		fileName := fmt.Sprintf("panel-%d.png", len(p.panels))
+		p.panels[fileName] = panel{dashboardUID: dashboardUID, panelID: panelID, startsAt: startsAt}
+		embed.Image = &EmbedImage{URL: attachmentScheme + fileName}
+		return
+	}
+}
+
+// renderPanelImages renders the panels referenced by the embeds, at most
+// maxFiles of them, and returns their files. The panels are rendered in
+// parallel, all within the render timeout. The embeds whose panel isn't
+// rendered are left text-only.
+func (p *panelRenderer) renderPanelImages(ctx context.Context, embeds []MessageEmbed, maxFiles int) []File {
+	if p.client == nil {
+		return []File{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.client.HTTPClient.Timeout)
+	defer cancel()
+
+	files := make([]*File, len(embeds))
+	var wg sync.WaitGroup
+
+	requested := 0
+	for i := range embeds {
+		embed := &embeds[i]
+		if embed.Image == nil || !strings.HasPrefix(embed.Image.URL, attachmentScheme) {
+			continue
+		}
+
+		fileName := strings.TrimPrefix(embed.Image.URL, attachmentScheme)
+		panel, ok := p.panels[fileName]
+		if !ok || requested == maxFiles {
+			embed.Image = nil
+			continue
+		}
+		requested++
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			image, err := p.client.RenderPanel(ctx, panel.dashboardUID, panel.panelID, panel.startsAt)
+			if err != nil {
+				logging.FromContext(ctx).Error("Sending embed without image",
+					"dashboardUID", panel.dashboardUID, "panelID", panel.panelID, "error", err)
+				return
+			}
+			files[i] = &File{Name: fileName, ContentType: "image/png", Data: image}
+		}(i)
+	}
+
+	wg.Wait()
+
+	rendered := []File{}
+	for i, file := range files {
+		if file != nil {
+			rendered = append(rendered, *file)
+		} else {
+			embeds[i].Image = nil
+		}
+	}
+	return rendered
+}
@@ -0,0 +1,282 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+var pngImage = []byte("\x89PNG\r\n\x1a\nfake panel")
+
+// loadTestConfig loads a config file with the given contents, on top of the
+// defaults
+func loadTestConfig(t *testing.T, contents string) config.Config {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return *config.LoadUserConfig([]string{"--config", path})
+}
+
+func TestSendAlertsAttachesPanelImages(t *testing.T) {
+	var renderedPanels []string
+	grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer grafana-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		renderedPanels = append(renderedPanels, r.URL.Query().Get("panelId"))
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngImage)
+	}))
+	defer grafanaServer.Close()
+
+	type part struct {
+		fileName string
+		data     []byte
+	}
+	parts := map[string]part{}
+	discordServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Errorf("Expected a multipart body: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for {
+			p, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("Invalid multipart body: %v", err)
+				break
+			}
+			data, _ := io.ReadAll(p)
+			parts[p.FormName()] = part{fileName: p.FileName(), data: data}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer discordServer.Close()
+
+	configs := loadTestConfig(t, `
+grafanaImage:
+  enabled: true
+  url: `+grafanaServer.URL+`
+  apiToken: grafana-token
+channels:
+  default:
+    name: default
+    webhookURL: `+discordServer.URL+`/api/webhooks/1/token
+`)
+
+	body := alertmanager.MessageBody{
+		Status: "firing",
+		Alerts: []alertmanager.Alert{
+			{
+				Status:   "firing",
+				Labels:   map[string]string{"alertname": "HighLatency", "severity": "critical"},
+				StartsAt: "2024-01-01T00:00:00Z",
+				Annotations: map[string]string{
+					"summary":          "Latency is high",
+					"__dashboardUid__": "abc",
+					"__panelId__":      "2",
+				},
+			},
+			{
+				Status:   "firing",
+				Labels:   map[string]string{"alertname": "HighErrorRate", "severity": "warning"},
+				StartsAt: "2024-01-01T00:00:00Z",
+				Annotations: map[string]string{
+					"summary":          "Errors are high",
+					"__dashboardUid__": "abc",
+					"__panelId__":      "3",
+				},
+			},
+			{
+				Status:      "firing",
+				Labels:      map[string]string{"alertname": "NoPanel", "severity": "warning"},
+				StartsAt:    "2024-01-01T00:00:00Z",
+				Annotations: map[string]string{"summary": "No dashboard"},
+			},
+		},
+	}
+
+	result, err := SendAlerts(context.Background(), "default", body, configs)
+	if err != nil {
+		t.Fatalf("SendAlerts: %v", err)
+	}
+	if result.MessageID != "1" {
+		t.Errorf("Expected message ID 1, got %q", result.MessageID)
+	}
+	if len(renderedPanels) != 2 {
+		t.Fatalf("Expected 2 rendered panels, got %v", renderedPanels)
+	}
+
+	payload, ok := parts["payload_json"]
+	if !ok {
+		t.Fatalf("Missing payload_json part, got %v", parts)
+	}
+	var message WebhookParams
+	if err := json.Unmarshal(payload.data, &message); err != nil {
+		t.Fatalf("Invalid payload_json: %v", err)
+	}
+
+	var attachments []string
+	for _, embed := range message.Embeds {
+		if embed.Image != nil {
+			attachments = append(attachments, embed.Image.URL)
+		}
+	}
+	if len(attachments) != 2 {
+		t.Fatalf("Expected 2 embeds with an image, got %v", attachments)
+	}
+
+	for i, fileName := range []string{"panel-0.png", "panel-1.png"} {
+		file, ok := parts[fmt.Sprintf("files[%d]", i)]
+		if !ok {
+			t.Fatalf("Missing files[%d] part", i)
+		}
+		if file.fileName != fileName {
+			t.Errorf("files[%d]: expected file name %s, got %s", i, fileName, file.fileName)
+		}
+		if !bytes.Equal(file.data, pngImage) {
+			t.Errorf("files[%d]: expected the rendered PNG, got %q", i, file.data)
+		}
+		if !contains(attachments, "attachment://"+fileName) {
+			t.Errorf("Expected an embed referencing attachment://%s, got %v", fileName, attachments)
+		}
+	}
+	if _, ok := parts["files[2]"]; ok {
+		t.Errorf("Expected no file for the alert without a panel")
+	}
+}
+
+func TestPanelImagesOfKeptEmbeds(t *testing.T) {
+	panelAlerts := func(count, descriptionSize int) alertmanager.MessageBody {
+		body := alertmanager.MessageBody{Status: "firing"}
+		for i := 0; i < count; i++ {
+			body.Alerts = append(body.Alerts, alertmanager.Alert{
+				Status:   "firing",
+				Labels:   map[string]string{"alertname": fmt.Sprintf("Alert%02d", i), "severity": "critical"},
+				StartsAt: "2024-01-01T00:00:00Z",
+				Annotations: map[string]string{
+					"description":      strings.Repeat("d", descriptionSize),
+					"__dashboardUid__": "abc",
+					"__panelId__":      fmt.Sprint(i),
+				},
+			})
+		}
+		return body
+	}
+
+	tests := []struct {
+		name            string
+		delay           time.Duration
+		when            string
+		alerts          int
+		descriptionSize int
+		wantEmbeds      int
+		wantPanels      int
+		wantDetails     bool
+	}{
+		// 15 embeds of about 600 characters overflow, 9 are kept with the
+		// summary embed
+		{"only the kept embeds", 200 * time.Millisecond, "overflow", 15, 600, 10, 9, true},
+		{"room for the details file", 0, "always", 10, 10, 10, 9, true},
+		{"render timeout", 2 * time.Second, "overflow", 3, 10, 3, 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var mutex sync.Mutex
+			rendered := 0
+			grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(test.delay):
+				case <-r.Context().Done():
+					return
+				}
+				mutex.Lock()
+				rendered++
+				mutex.Unlock()
+				w.Header().Set("Content-Type", "image/png")
+				w.Write(pngImage)
+			}))
+			defer grafanaServer.Close()
+
+			configs := loadTestConfig(t, `
+grafanaImage:
+  enabled: true
+  url: `+grafanaServer.URL+`
+  timeout: 1s
+alertDetails:
+  enabled: true
+  when: `+test.when+`
+channels:
+  default:
+    name: default
+    webhookURL: https://discord.com/api/webhooks/1/token
+`)
+
+			start := time.Now()
+			preview, err := PreviewAlerts(context.Background(), "default", panelAlerts(test.alerts, test.descriptionSize), configs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Rendered one after the other, the panels would take longer
+			if elapsed := time.Since(start); elapsed > 1500*time.Millisecond {
+				t.Errorf("Rendering took %s", elapsed)
+			}
+
+			if len(preview.Files) > maxFilesPerMessage {
+				t.Errorf("%d files attached", len(preview.Files))
+			}
+
+			panels := 0
+			details := false
+			for _, file := range preview.Files {
+				if strings.HasPrefix(file.Name, "panel-") {
+					panels++
+				} else {
+					details = true
+				}
+			}
+			images := 0
+			for _, embed := range preview.Message.Embeds {
+				if embed.Image != nil {
+					images++
+				}
+			}
+
+			if len(preview.Message.Embeds) != test.wantEmbeds {
+				t.Errorf("%d embeds, want %d", len(preview.Message.Embeds), test.wantEmbeds)
+			}
+			if panels != test.wantPanels || images != test.wantPanels {
+				t.Errorf("%d panels attached and %d embeds with an image, want %d", panels, images, test.wantPanels)
+			}
+			if test.delay < time.Second && rendered != test.wantPanels {
+				t.Errorf("%d panels rendered, want %d", rendered, test.wantPanels)
+			}
+			if details != test.wantDetails {
+				t.Errorf("Details attached = %v, want %v", details, test.wantDetails)
+			}
+		})
+	}
+}
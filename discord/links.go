@@ -0,0 +1,360 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/logging"
+)
+
+// linkTemplateData is the data available to the link URL templates. Labels,
+// Annotations and GeneratorURL are the ones of each alert of the group
+type linkTemplateData struct {
+	Labels            map[string]string
+	Annotations       map[string]string
+	GeneratorURL      string
+	GroupLabels       map[string]string
+	CommonLabels      map[string]string
+	CommonAnnotations map[string]string
+	ExternalURL       string
+	Receiver          string
+}
+
+// linkDefinition is a configured link with its parsed URL template
+type linkDefinition struct {
+	config.LinkConfig
+	urlTemplate *template.Template
+}
+
+// groupLink is a link resolved for an embed group. When the group's alerts
+// don't share the same URL and per-alert links are enabled, AlertURLs holds
+// the URL of each alert, in the group's order, instead of URL. Both are
+// empty when no alert of the group has the link
+type groupLink struct {
+	config.LinkConfig
+	URL       string
+	AlertURLs []string
+}
+
+// groupLinks holds the resolved links of an embed group, one for each link
+// definition
+type groupLinks struct {
+	Title string
+	Links []groupLink
+}
+
+// getLinkConfigs returns the configured links, preceded by the ones defined
+// through the dashboardLink and generatorLink configs
+func getLinkConfigs(configs config.Config) []config.LinkConfig {
+	linkConfigs := []config.LinkConfig{}
+
+	if configs.DashboardLink.Enabled {
+		labelName := configs.DashboardLink.Label
+		linkConfigs = append(linkConfigs, config.LinkConfig{
+			Text: configs.DashboardLink.Text,
+			URL: fmt.Sprintf(
+				`{{ or (index .Labels %[1]q) (index .Annotations %[1]q) (index .GroupLabels %[1]q) `+
+					`(index .CommonLabels %[1]q) (index .CommonAnnotations %[1]q) }}`,
+				labelName),
+			Position: configs.DashboardLink.Position,
+			PerAlert: configs.DashboardLink.PerAlert,
+		})
+	}
+
+	if configs.GeneratorLink.Enabled {
+		linkConfigs = append(linkConfigs, config.LinkConfig{
+			Text:     configs.GeneratorLink.Text,
+			URL:      "{{ .GeneratorURL }}",
+			Position: configs.GeneratorLink.Position,
+			PerAlert: configs.GeneratorLink.PerAlert,
+		})
+	}
+
+	return append(linkConfigs, configs.Links...)
+}
+
+// parseLinkDefinitions parses the URL template of each link. The templates
+// are validated when loading the config, any invalid one is still logged and
+// skipped, so the alerts are sent
+func parseLinkDefinitions(ctx context.Context, configs config.Config) []linkDefinition {
+	linkDefinitions := []linkDefinition{}
+
+	for _, linkConfig := range getLinkConfigs(configs) {
+		urlTemplate, err := config.ParseLinkTemplate(linkConfig)
+		if err != nil {
+			logging.FromContext(ctx).Error("Skipping link with invalid template",
+				"link", linkConfig.Text, "error", err)
+			continue
+		}
+
+		linkDefinitions = append(linkDefinitions, linkDefinition{
+			LinkConfig:  linkConfig,
+			urlTemplate: urlTemplate,
+		})
+	}
+
+	return linkDefinitions
+}
+
+// resolveGroupLinks resolves the links of each group, keyed by group key.
+// Groups are resolved with their own alerts, so alerts with different
+// alertnames don't share the same query or dashboard
+func resolveGroupLinks(
+	ctx context.Context,
+	alertsGroupedByName alertmanager.AlertsGroupedByLabel,
+	linkDefinitions []linkDefinition,
+	alertmanagerBodyInfo alertmanager.MessageBodyInfo,
+	configs config.Config) map[string]groupLinks {
+
+	linksByGroup := make(map[string]groupLinks, len(alertsGroupedByName))
+
+	for groupKey, groupData := range alertsGroupedByName {
+		groupData.Alerts = sortAlerts(groupData.Alerts, configs)
+
+		links := groupLinks{Title: getGroupTitle(groupData)}
+
+		for _, linkDefinition := range linkDefinitions {
+			links.Links = append(links.Links,
+				resolveGroupLink(ctx, groupData.Alerts, linkDefinition, alertmanagerBodyInfo))
+		}
+
+		linksByGroup[groupKey] = links
+	}
+
+	return linksByGroup
+}
+
+// resolveGroupLink evaluates the link for each alert in the group. A URL
+// shared by the whole group, or the first one found when per-alert links are
+// disabled, is used once for the group
+func resolveGroupLink(
+	ctx context.Context,
+	alerts []alertmanager.Alert,
+	linkDefinition linkDefinition,
+	alertmanagerBodyInfo alertmanager.MessageBodyInfo) groupLink {
+
+	alertURLs := make([]string, len(alerts))
+	distinctURLs := []string{}
+
+	for i, alert := range alerts {
+		alertURLs[i] = evaluateLink(ctx, linkDefinition, alert, alertmanagerBodyInfo)
+		if alertURLs[i] != "" && !contains(distinctURLs, alertURLs[i]) {
+			distinctURLs = append(distinctURLs, alertURLs[i])
+		}
+	}
+
+	link := groupLink{LinkConfig: linkDefinition.LinkConfig}
+
+	switch {
+	case len(distinctURLs) == 0:
+	case len(distinctURLs) == 1 || !linkDefinition.PerAlert:
+		link.URL = distinctURLs[0]
+	default:
+		link.AlertURLs = alertURLs
+	}
+
+	return link
+}
+
+// evaluateLink returns the link's URL for an alert, or an empty string if the
+// alert doesn't meet the link's condition
+func evaluateLink(
+	ctx context.Context,
+	linkDefinition linkDefinition,
+	alert alertmanager.Alert,
+	alertmanagerBodyInfo alertmanager.MessageBodyInfo) string {
+
+	if condition := linkDefinition.Condition; condition != "" {
+		if alert.Labels[condition] == "" && alert.Annotations[condition] == "" {
+			return ""
+		}
+	}
+
+	var urlBuilder bytes.Buffer
+	err := linkDefinition.urlTemplate.Execute(&urlBuilder, linkTemplateData{
+		Labels:            alert.Labels,
+		Annotations:       alert.Annotations,
+		GeneratorURL:      alert.GeneratorURL,
+		GroupLabels:       alertmanagerBodyInfo.GroupLabels,
+		CommonLabels:      alertmanagerBodyInfo.CommonLabels,
+		CommonAnnotations: alertmanagerBodyInfo.CommonAnnotations,
+		ExternalURL:       alertmanagerBodyInfo.ExternalURL,
+		Receiver:          alertmanagerBodyInfo.Receiver,
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Skipping link",
+			"link", linkDefinition.Text, "alertname", alert.Labels["alertname"], "error", err)
+		return ""
+	}
+
+	return strings.TrimSpace(urlBuilder.String())
+}
+
+// writeContentLinks writes the group links positioned in the message content,
+// deduplicated between groups. When groups have different URLs for the same
+// link, each one is labeled with the title of its group. The content is kept
+// within limit characters, the links that don't fit being counted instead
+func writeContentLinks(
+	contentBuilder *strings.Builder,
+	linkDefinitions []linkDefinition,
+	linksByStatus []map[string]groupLinks,
+	limit int) {
+
+	type contentLink struct {
+		title, url string
+	}
+
+	lines := []string{}
+
+	for linkIndex, linkDefinition := range linkDefinitions {
+		if linkDefinition.Position != "content" {
+			continue
+		}
+
+		seen := map[string]bool{}
+		distinct := []contentLink{}
+
+		for _, linksByGroup := range linksByStatus {
+			for _, groupKey := range sortedGroupKeys(linksByGroup) {
+				links := linksByGroup[groupKey]
+				for _, url := range links.Links[linkIndex].urls() {
+					if !seen[url] {
+						seen[url] = true
+						distinct = append(distinct, contentLink{links.Title, url})
+					}
+				}
+			}
+		}
+
+		for _, link := range distinct {
+			if len(distinct) == 1 {
+				lines = append(lines, fmt.Sprintf("[%s](%s)", linkDefinition.Text, link.url))
+			} else {
+				lines = append(lines, fmt.Sprintf("[%s: %s](%s)", linkDefinition.Text, link.title, link.url))
+			}
+		}
+	}
+
+	size := utf8.RuneCountInString(contentBuilder.String())
+	for i, line := range lines {
+		// Each line needs room for the count of the ones after it
+		needed := 1 + utf8.RuneCountInString(line)
+		if remaining := len(lines) - i - 1; remaining > 0 {
+			needed += 1 + utf8.RuneCountInString(moreContentLinks(remaining))
+		}
+
+		if size+needed > limit {
+			writeContentLine(contentBuilder, moreContentLinks(len(lines)-i))
+			return
+		}
+
+		writeContentLine(contentBuilder, line)
+		size += 1 + utf8.RuneCountInString(line)
+	}
+}
+
+func moreContentLinks(count int) string {
+	return fmt.Sprintf("… and %d more links", count)
+}
+
+func writeContentLine(contentBuilder *strings.Builder, line string) {
+	if contentBuilder.Len() > 0 {
+		contentBuilder.WriteString("\n")
+	}
+	contentBuilder.WriteString(line)
+}
+
+// handleEmbedLinks writes the embed description with the group-wide links
+// positioned around it, and adds the ones positioned as fields
+func handleEmbedLinks(embed *MessageEmbed, links groupLinks, description string) {
+	var topLinks, bottomLinks []string
+
+	for _, link := range links.Links {
+		if link.URL == "" {
+			continue
+		}
+
+		markdownLink := fmt.Sprintf("[%s](%s)", link.Text, link.URL)
+
+		switch link.Position {
+		case "embed_top":
+			topLinks = append(topLinks, markdownLink)
+		case "embed_bottom":
+			bottomLinks = append(bottomLinks, markdownLink)
+		case "field":
+			embed.Fields = append(embed.Fields, EmbedField{
+				Name:   link.Text,
+				Value:  markdownLink,
+				Inline: true,
+			})
+		}
+	}
+
+	var descriptionBuilder strings.Builder
+	descriptionBuilder.WriteString("### " + embed.Title + "\n")
+	if len(topLinks) > 0 {
+		descriptionBuilder.WriteString(strings.Join(topLinks, "\n") + "\n")
+	}
+	descriptionBuilder.WriteString(description)
+	if len(bottomLinks) > 0 {
+		descriptionBuilder.WriteString("\n" + strings.Join(bottomLinks, "\n"))
+	}
+
+	embed.Description = descriptionBuilder.String()
+}
+
+// formatAlertLinks returns the per-alert links of the alert at index i of the
+// group, shown below its description. Links positioned in the content are
+// already listed there
+func formatAlertLinks(links groupLinks, i int) string {
+	var linksBuilder strings.Builder
+
+	for _, link := range links.Links {
+		if link.Position == "content" || len(link.AlertURLs) <= i || link.AlertURLs[i] == "" {
+			continue
+		}
+		linksBuilder.WriteString(fmt.Sprintf("[%s](%s)\n", link.Text, link.AlertURLs[i]))
+	}
+
+	return linksBuilder.String()
+}
+
+// urls returns the distinct URLs of the link, in order
+func (l groupLink) urls() []string {
+	if l.URL != "" {
+		return []string{l.URL}
+	}
+
+	urls := []string{}
+	for _, url := range l.AlertURLs {
+		if url != "" && !contains(urls, url) {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+func sortedGroupKeys(linksByGroup map[string]groupLinks) []string {
+	groupKeys := make([]string, 0, len(linksByGroup))
+	for groupKey := range linksByGroup {
+		groupKeys = append(groupKeys, groupKey)
+	}
+	sort.Strings(groupKeys)
+	return groupKeys
+}
+
+func contains(s []string, str string) bool {
+	for _, v := range s {
+		if v == str {
+			return true
+		}
+	}
+	return false
+}
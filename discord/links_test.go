@@ -0,0 +1,220 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+func TestWriteContentLinks(t *testing.T) {
+	generatorLink := config.LinkConfig{Text: "Open in PromQL", Position: "content"}
+	fieldLink := config.LinkConfig{Text: "Runbook", Position: "field"}
+	linkDefinitions := []linkDefinition{{LinkConfig: generatorLink}, {LinkConfig: fieldLink}}
+
+	groups := func(count int, url func(i int) string) map[string]groupLinks {
+		linksByGroup := map[string]groupLinks{}
+		for i := 0; i < count; i++ {
+			linksByGroup[fmt.Sprintf("Alert%03d", i)] = groupLinks{
+				Title: fmt.Sprintf("Alert%03d", i),
+				Links: []groupLink{
+					{LinkConfig: generatorLink, URL: url(i)},
+					{LinkConfig: fieldLink, URL: "https://runbooks.example.com"},
+				},
+			}
+		}
+		return linksByGroup
+	}
+	sharedURL := func(int) string { return "https://prometheus.example.com/graph" }
+	groupURL := func(i int) string {
+		return fmt.Sprintf("https://prometheus.example.com/graph?g0.expr=up%%7Bjob%%3D%%22job-%d%%22%%7D", i)
+	}
+
+	tests := []struct {
+		name      string
+		content   string
+		groups    map[string]groupLinks
+		want      string
+		wantLinks int
+	}{
+		{"deduplicated", "", groups(3, sharedURL), "[Open in PromQL](https://prometheus.example.com/graph)", 1},
+		{"labeled by group", "<@&1>", groups(2, groupURL),
+			"<@&1>\n[Open in PromQL: Alert000](" + groupURL(0) + ")\n[Open in PromQL: Alert001](" + groupURL(1) + ")", 2},
+		{"many groups", "<@&1>", groups(200, groupURL), "", 200},
+		{"no room", strings.Repeat("m", 990), groups(2, groupURL), strings.Repeat("m", 990) + "\n… and 2 more links", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			const limit = 1010
+
+			var contentBuilder strings.Builder
+			contentBuilder.WriteString(test.content)
+			writeContentLinks(&contentBuilder, linkDefinitions, []map[string]groupLinks{test.groups}, limit)
+			content := contentBuilder.String()
+
+			if size := utf8.RuneCountInString(content); size > limit {
+				t.Errorf("Content of %d characters, limit %d", size, limit)
+			}
+			if test.want != "" && content != test.want {
+				t.Errorf("Content = %q, want %q", content, test.want)
+			}
+
+			// Every link is either shown or counted
+			more := 0
+			fmt.Sscanf(content[strings.LastIndex(content, "\n")+1:], "… and %d more links", &more)
+			links := strings.Count(content, "[Open in PromQL")
+			if links+more != test.wantLinks {
+				t.Errorf("%d links shown and %d counted, want %d", links, more, test.wantLinks)
+			}
+		})
+	}
+}
+
+func testLinkDefinition(t *testing.T, linkConfig config.LinkConfig) linkDefinition {
+	t.Helper()
+	urlTemplate, err := config.ParseLinkTemplate(linkConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return linkDefinition{LinkConfig: linkConfig, urlTemplate: urlTemplate}
+}
+
+func TestEvaluateLink(t *testing.T) {
+	alert := alertmanager.Alert{
+		Labels:       map[string]string{"alertname": "HighLatency", "pod": "api-0", "namespace": "prod/eu"},
+		Annotations:  map[string]string{"runbook_url": "https://runbooks.example.com/latency"},
+		GeneratorURL: "https://prometheus.example.com/graph",
+	}
+	info := alertmanager.MessageBodyInfo{
+		GroupLabels:       map[string]string{"alertname": "HighLatency"},
+		CommonLabels:      map[string]string{"cluster": "eu-1"},
+		CommonAnnotations: map[string]string{"dashboard": "https://grafana.example.com/d/latency"},
+		ExternalURL:       "http://alertmanager:9093",
+		Receiver:          "discord",
+	}
+
+	tests := []struct {
+		name       string
+		linkConfig config.LinkConfig
+		want       string
+	}{
+		{"annotation", config.LinkConfig{URL: "{{ .Annotations.runbook_url }}"}, "https://runbooks.example.com/latency"},
+		{"generator URL", config.LinkConfig{URL: "{{ .GeneratorURL }}"}, "https://prometheus.example.com/graph"},
+		{"notification data", config.LinkConfig{
+			URL: "{{ .ExternalURL }}/{{ .Receiver }}/{{ .GroupLabels.alertname }}/{{ .CommonLabels.cluster }}",
+		}, "http://alertmanager:9093/discord/HighLatency/eu-1"},
+		{"escaping", config.LinkConfig{
+			URL: `https://logs.example.com/{{ pathEscape .Labels.namespace }}?query={{ queryEscape (printf "{pod=%q}" .Labels.pod) }}`,
+		}, "https://logs.example.com/prod%2Feu?query=%7Bpod%3D%22api-0%22%7D"},
+		{"missing label", config.LinkConfig{URL: "{{ .Labels.trace_id }}"}, ""},
+		{"trimmed", config.LinkConfig{URL: " {{ .GeneratorURL }}\n"}, "https://prometheus.example.com/graph"},
+		{"condition met", config.LinkConfig{URL: "{{ .GeneratorURL }}", Condition: "runbook_url"},
+			"https://prometheus.example.com/graph"},
+		{"condition not met", config.LinkConfig{URL: "{{ .GeneratorURL }}", Condition: "trace_id"}, ""},
+		{"dashboard link", getLinkConfigs(config.Config{
+			DashboardLink: config.DashboardLinkConfig{Enabled: true, Label: "dashboard"},
+		})[0], "https://grafana.example.com/d/latency"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			linkDefinition := testLinkDefinition(t, test.linkConfig)
+			if got := evaluateLink(context.Background(), linkDefinition, alert, info); got != test.want {
+				t.Errorf("evaluateLink() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveGroupLink(t *testing.T) {
+	alert := func(runbook string) alertmanager.Alert {
+		return alertmanager.Alert{Annotations: map[string]string{"runbook_url": runbook}}
+	}
+
+	tests := []struct {
+		name          string
+		alerts        []alertmanager.Alert
+		perAlert      bool
+		wantURL       string
+		wantAlertURLs []string
+	}{
+		{"shared URL", []alertmanager.Alert{alert("a"), alert("a")}, true, "a", nil},
+		{"without the link", []alertmanager.Alert{alert(""), alert("")}, true, "", nil},
+		{"some alerts without the link", []alertmanager.Alert{alert(""), alert("a")}, true, "a", nil},
+		{"per alert", []alertmanager.Alert{alert("a"), alert(""), alert("b")}, true, "", []string{"a", "", "b"}},
+		{"first URL", []alertmanager.Alert{alert("a"), alert("b")}, false, "a", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			linkDefinition := testLinkDefinition(t, config.LinkConfig{
+				Text: "Runbook", URL: "{{ .Annotations.runbook_url }}", PerAlert: test.perAlert,
+			})
+			link := resolveGroupLink(context.Background(), test.alerts, linkDefinition, alertmanager.MessageBodyInfo{})
+			if link.URL != test.wantURL || !reflect.DeepEqual(link.AlertURLs, test.wantAlertURLs) {
+				t.Errorf("resolveGroupLink() = %q %v, want %q %v", link.URL, link.AlertURLs, test.wantURL, test.wantAlertURLs)
+			}
+		})
+	}
+}
+
+func TestHandleEmbedLinks(t *testing.T) {
+	link := func(text, position, url string) groupLink {
+		return groupLink{LinkConfig: config.LinkConfig{Text: text, Position: position}, URL: url}
+	}
+	links := groupLinks{Links: []groupLink{
+		link("Dashboard", "embed_top", "https://grafana.example.com"),
+		link("Query", "content", "https://prometheus.example.com"),
+		link("Logs", "embed_bottom", "https://logs.example.com"),
+		link("Runbook", "field", "https://runbooks.example.com"),
+		link("Traces", "embed_bottom", ""),
+		{LinkConfig: config.LinkConfig{Text: "Tickets", Position: "embed_top"}, AlertURLs: []string{"https://tickets.example.com"}},
+	}}
+
+	embed := MessageEmbed{Title: "HighLatency"}
+	handleEmbedLinks(&embed, links, "Latency is high")
+
+	wantDescription := "### HighLatency\n[Dashboard](https://grafana.example.com)\n" +
+		"Latency is high\n[Logs](https://logs.example.com)"
+	if embed.Description != wantDescription {
+		t.Errorf("Description = %q, want %q", embed.Description, wantDescription)
+	}
+
+	wantFields := []EmbedField{{Name: "Runbook", Value: "[Runbook](https://runbooks.example.com)", Inline: true}}
+	if !reflect.DeepEqual(embed.Fields, wantFields) {
+		t.Errorf("Fields = %v, want %v", embed.Fields, wantFields)
+	}
+}
+
+func TestFormatAlertLinks(t *testing.T) {
+	link := func(text, position string, alertURLs ...string) groupLink {
+		return groupLink{LinkConfig: config.LinkConfig{Text: text, Position: position}, AlertURLs: alertURLs}
+	}
+	links := groupLinks{Links: []groupLink{
+		link("Logs", "embed_bottom", "https://logs.example.com/0", "https://logs.example.com/1"),
+		link("Query", "content", "https://prometheus.example.com/0", "https://prometheus.example.com/1"),
+		link("Runbook", "field", "", "https://runbooks.example.com/1"),
+		{LinkConfig: config.LinkConfig{Text: "Dashboard", Position: "embed_top"}, URL: "https://grafana.example.com"},
+	}}
+
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "[Logs](https://logs.example.com/0)\n"},
+		{1, "[Logs](https://logs.example.com/1)\n[Runbook](https://runbooks.example.com/1)\n"},
+		{2, ""},
+	}
+
+	for _, test := range tests {
+		if got := formatAlertLinks(links, test.index); got != test.want {
+			t.Errorf("formatAlertLinks(%d) = %q, want %q", test.index, got, test.want)
+		}
+	}
+}
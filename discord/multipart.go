@@ -0,0 +1,51 @@
+package discord
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+)
+
+// encodeMessage builds the request body for the webhook. Messages without
+// files are sent as plain JSON, otherwise the JSON message goes in the
+// "payload_json" part and each file in a "files[n]" part
+func encodeMessage(jsonMessage []byte, files []File) (body []byte, contentType string, err error) {
+	if len(files) == 0 {
+		return jsonMessage, "application/json", nil
+	}
+
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+
+	payloadHeader := textproto.MIMEHeader{}
+	payloadHeader.Set("Content-Disposition", `form-data; name="payload_json"`)
+	payloadHeader.Set("Content-Type", "application/json")
+	payloadPart, err := writer.CreatePart(payloadHeader)
+	if err != nil {
+		return nil, "", fmt.Errorf("discord.encodeMessage: Error creating payload part \n%+v", err)
+	}
+	if _, err := payloadPart.Write(jsonMessage); err != nil {
+		return nil, "", fmt.Errorf("discord.encodeMessage: Error writing payload part \n%+v", err)
+	}
+
+	for i, file := range files {
+		fileHeader := textproto.MIMEHeader{}
+		fileHeader.Set("Content-Disposition",
+			fmt.Sprintf(`form-data; name="files[%d]"; filename="%s"`, i, file.Name))
+		fileHeader.Set("Content-Type", file.ContentType)
+		filePart, err := writer.CreatePart(fileHeader)
+		if err != nil {
+			return nil, "", fmt.Errorf("discord.encodeMessage: Error creating file part \n%+v", err)
+		}
+		if _, err := filePart.Write(file.Data); err != nil {
+			return nil, "", fmt.Errorf("discord.encodeMessage: Error writing file part \n%+v", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("discord.encodeMessage: Error closing multipart writer \n%+v", err)
+	}
+
+	return buffer.Bytes(), writer.FormDataContentType(), nil
+}
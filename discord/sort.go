@@ -0,0 +1,143 @@
+package discord
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+// sortEmbedQueue orders the embeds by the configured keys. The group key is
+// always the last tie-breaker, so the order doesn't depend on map iteration
+func sortEmbedQueue(embedQueue []EmbedQueueItem, sortKeys []string) {
+	sort.SliceStable(embedQueue, func(i, j int) bool {
+		a, b := embedQueue[i], embedQueue[j]
+
+		for _, sortKey := range sortKeys {
+			key, descending := parseSortKey(sortKey)
+
+			var comparison int
+			switch key {
+			case "priority":
+				comparison = -compareInts(a.Priority, b.Priority)
+			case "count":
+				comparison = -compareInts(a.Count, b.Count)
+			case "startsAt":
+				comparison = compareTimes(a.StartsAt, b.StartsAt)
+			case "title":
+				comparison = strings.Compare(a.Title, b.Title)
+			}
+
+			if descending {
+				comparison = -comparison
+			}
+			if comparison != 0 {
+				return comparison < 0
+			}
+		}
+
+		return a.GroupKey < b.GroupKey
+	})
+}
+
+// sortAlerts orders the alerts inside a group by the configured keys,
+// keeping the payload order for ties
+func sortAlerts(alerts []alertmanager.Alert, configs config.Config) []alertmanager.Alert {
+	sortKeys := configs.Sort.Alerts
+	if len(sortKeys) == 0 {
+		return alerts
+	}
+
+	sorted := make([]alertmanager.Alert, len(alerts))
+	copy(sorted, alerts)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+
+		for _, sortKey := range sortKeys {
+			key, descending := parseSortKey(sortKey)
+
+			var comparison int
+			switch {
+			case key == "startsAt":
+				comparison = compareTimes(parseAlertTime(a.StartsAt), parseAlertTime(b.StartsAt))
+			case key == "severity":
+				comparison = -compareInts(
+					getSeverityAppearance(a.Severity, configs).Priority,
+					getSeverityAppearance(b.Severity, configs).Priority)
+			case strings.HasPrefix(key, "label:"):
+				labelName := strings.TrimPrefix(key, "label:")
+				comparison = strings.Compare(a.Labels[labelName], b.Labels[labelName])
+			}
+
+			if descending {
+				comparison = -comparison
+			}
+			if comparison != 0 {
+				return comparison < 0
+			}
+		}
+
+		return false
+	})
+
+	return sorted
+}
+
+// getEarliestStartsAt returns the earliest StartsAt among the alerts, or the
+// zero time if none can be parsed
+func getEarliestStartsAt(alerts []alertmanager.Alert) time.Time {
+	var earliest time.Time
+	for _, alert := range alerts {
+		startsAt := parseAlertTime(alert.StartsAt)
+		if startsAt.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || startsAt.Before(earliest) {
+			earliest = startsAt
+		}
+	}
+	return earliest
+}
+
+func parseSortKey(sortKey string) (key string, descending bool) {
+	if strings.HasPrefix(sortKey, "-") {
+		return strings.TrimPrefix(sortKey, "-"), true
+	}
+	return sortKey, false
+}
+
+func parseAlertTime(value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}
+	}
+	return parsed
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareTimes orders zero times last
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Equal(b):
+		return 0
+	case a.IsZero():
+		return 1
+	case b.IsZero():
+		return -1
+	case a.Before(b):
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,240 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/logging"
+	"github.com/kolesaev/alertmanager-discord/state"
+)
+
+// maxFieldLength is Discord's limit for an embed field's value
+const maxFieldLength = 1024
+
+// SummaryReport is the content of a channel's scheduled summary
+type SummaryReport struct {
+	Channel string
+	Time    time.Time
+	// Firing holds the alerts still firing in the channel
+	Firing []state.Alert
+	// FiredCount and ResolvedCount are the distinct alerts received firing
+	// and resolved over Period. They come from the history, and
+	// CountsUnavailable is set when it's disabled or can't be read
+	Period            time.Duration
+	FiredCount        int
+	ResolvedCount     int
+	CountsUnavailable bool
+	// LongestFiring is the number of longest firing alerts listed
+	LongestFiring int
+}
+
+// SendSummary posts the summary report to its channel
+func SendSummary(ctx context.Context, report SummaryReport, configs config.Config) (DeliveryResult, error) {
+	if _, err := getDiscordChannel(report.Channel, configs); err != nil {
+		return DeliveryResult{}, fmt.Errorf("discord.SendSummary: Error trying to get Discord Channel \n%+v", err)
+	}
+
+	result, err := sendMessage(ctx, report.Channel, createSummaryMessage(report, configs), nil, configs, nil)
+	if err != nil {
+		return result, fmt.Errorf("discord.SendSummary: Error sending the summary \n%+v", err)
+	}
+
+	logging.FromContext(ctx).Info("Sent summary to Discord",
+		"channel", report.Channel, "alertCount", len(report.Firing), "messageID", result.MessageID)
+
+	return result, nil
+}
+
+// severitySummary holds the firing alerts of a severity, counted by name
+type severitySummary struct {
+	severity   string
+	appearance config.SeverityAppearance
+	counts     map[string]int
+	total      int
+}
+
+// createSummaryMessage renders the report as an embed, with a field for each
+// severity, ordered by priority, then the longest firing alerts and the
+// counts over the period. The embed takes the color of the highest severity
+func createSummaryMessage(report SummaryReport, configs config.Config) WebhookParams {
+	channelTitle := report.Channel
+	if name := configs.DiscordChannels[report.Channel].Name; name != "" {
+		channelTitle = name
+	}
+
+	embed := MessageEmbed{
+		Title:     fmt.Sprintf(":clipboard: Summary for %s", channelTitle),
+		Timestamp: report.Time.Format(time.RFC3339),
+		Color:     configs.Status["resolved"].Color,
+	}
+
+	if len(report.Firing) == 0 {
+		embed.Description = "No alerts are firing."
+	} else {
+		embed.Description = fmt.Sprintf("%d alerts are firing.", len(report.Firing))
+	}
+
+	severities := summarizeSeverities(report.Firing, configs)
+	if len(severities) > 0 {
+		embed.Color = severities[0].appearance.Color
+	}
+
+	for _, summary := range severities {
+		embed.Fields = append(embed.Fields, EmbedField{
+			Name:  strings.TrimSpace(fmt.Sprintf("%s %s (%d)", summary.appearance.Emoji, summary.severity, summary.total)),
+			Value: formatAlertCounts(summary.counts),
+		})
+	}
+
+	if longest := formatLongestFiring(report); longest != "" {
+		embed.Fields = append(embed.Fields, EmbedField{
+			Name:  "Longest firing",
+			Value: longest,
+		})
+	}
+
+	counts := fmt.Sprintf("Fired: %d\nResolved: %d", report.FiredCount, report.ResolvedCount)
+	if report.CountsUnavailable {
+		counts = "Fired and resolved alerts are only counted with the history enabled"
+	}
+	embed.Fields = append(embed.Fields, EmbedField{
+		Name:  fmt.Sprintf("Last %s", formatDuration(report.Period)),
+		Value: counts,
+	})
+
+	return WebhookParams{
+		Username:  configs.Username,
+		AvatarURL: configs.AvatarURL,
+		Embeds:    []MessageEmbed{embed},
+	}
+}
+
+func summarizeSeverities(alerts []state.Alert, configs config.Config) []*severitySummary {
+	bySeverity := map[string]*severitySummary{}
+	for _, alert := range alerts {
+		severity := configs.Severity.Of(alert.Labels)
+
+		summary, ok := bySeverity[severity]
+		if !ok {
+			summary = &severitySummary{
+				severity:   severity,
+				appearance: getSeverityAppearance(severity, configs),
+				counts:     map[string]int{},
+			}
+			bySeverity[severity] = summary
+		}
+		summary.counts[alert.Labels["alertname"]]++
+		summary.total++
+	}
+
+	severities := make([]*severitySummary, 0, len(bySeverity))
+	for _, summary := range bySeverity {
+		severities = append(severities, summary)
+	}
+	sort.Slice(severities, func(i, j int) bool {
+		if severities[i].appearance.Priority != severities[j].appearance.Priority {
+			return severities[i].appearance.Priority > severities[j].appearance.Priority
+		}
+		return severities[i].severity < severities[j].severity
+	})
+
+	return severities
+}
+
+// formatAlertCounts lists the alert names, most frequent first
+func formatAlertCounts(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = fmt.Sprintf("%s × %d", name, counts[name])
+	}
+
+	return truncateField(lines)
+}
+
+func formatLongestFiring(report SummaryReport) string {
+	alerts := []state.Alert{}
+	for _, alert := range report.Firing {
+		if !alert.StartsAt.IsZero() {
+			alerts = append(alerts, alert)
+		}
+	}
+	sort.SliceStable(alerts, func(i, j int) bool {
+		return alerts[i].StartsAt.Before(alerts[j].StartsAt)
+	})
+	if len(alerts) > report.LongestFiring {
+		alerts = alerts[:report.LongestFiring]
+	}
+
+	lines := make([]string, len(alerts))
+	for i, alert := range alerts {
+		title := alert.Labels["alertname"]
+		if summary := alert.Annotations["summary"]; summary != "" {
+			title = summary
+		}
+		lines[i] = fmt.Sprintf("%s: %s", title, formatDuration(report.Time.Sub(alert.StartsAt)))
+	}
+
+	return truncateField(lines)
+}
+
+// truncateField joins the lines, replacing the last ones beyond Discord's
+// field length with their count. The first line is cut when it's too long on
+// its own. Lengths are counted in characters, as Discord does
+func truncateField(lines []string) string {
+	var builder strings.Builder
+	size := 0
+
+	for i, line := range lines {
+		if i > 0 {
+			line = "\n" + line
+		}
+
+		// Room is kept for the count of the lines after this one
+		reserve := 0
+		if remaining := len(lines) - i - 1; remaining > 0 {
+			reserve = utf8.RuneCountInString(moreLines(remaining))
+		}
+
+		if size+utf8.RuneCountInString(line)+reserve > maxFieldLength {
+			if i > 0 {
+				builder.WriteString(moreLines(len(lines) - i))
+				break
+			}
+			line = truncateLine(line, maxFieldLength-reserve)
+		}
+
+		builder.WriteString(line)
+		size += utf8.RuneCountInString(line)
+	}
+
+	return builder.String()
+}
+
+func moreLines(count int) string {
+	return fmt.Sprintf("\n… and %d more", count)
+}
+
+// truncateLine cuts line to limit characters, ending it with "…"
+func truncateLine(line string, limit int) string {
+	runes := []rune(line)
+	if len(runes) <= limit {
+		return line
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,91 @@
+package discord
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+var moreLinesPattern = regexp.MustCompile(`\n… and (\d+) more$`)
+
+func TestTruncateField(t *testing.T) {
+	repeatedLines := func(count int, line string) []string {
+		lines := make([]string, count)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("%s%d × 1", line, i)
+		}
+		return lines
+	}
+
+	tests := []struct {
+		name      string
+		lines     []string
+		wantShown int
+	}{
+		{"empty", nil, 0},
+		{"fits", []string{"HighLatency × 3", "HighErrorRate × 1"}, 2},
+		{"many short lines", repeatedLines(200, "Alert"), -1},
+		{"long alert names", repeatedLines(10, strings.Repeat("VeryLongAlertName", 10)), -1},
+		{"long last line", []string{strings.Repeat("a", 500), strings.Repeat("b", 500), strings.Repeat("c", 500)}, 2},
+		{"last line just too long", []string{strings.Repeat("a", 1000), strings.Repeat("b", 24)}, 1},
+		{"multibyte names", repeatedLines(100, strings.Repeat("🔥", 40)), -1},
+		{"single line too long", []string{strings.Repeat("x", 2000)}, 1},
+		{"first of several too long", []string{strings.Repeat("x", 2000), "b", "c"}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			field := truncateField(test.lines)
+
+			if size := utf8.RuneCountInString(field); size > maxFieldLength {
+				t.Fatalf("Field has %d characters", size)
+			}
+
+			shown := field
+			hidden := 0
+			if match := moreLinesPattern.FindStringSubmatch(field); match != nil {
+				shown = strings.TrimSuffix(field, match[0])
+				hidden, _ = strconv.Atoi(match[1])
+			}
+
+			shownLines := 0
+			if shown != "" {
+				shownLines = strings.Count(shown, "\n") + 1
+			}
+			if shownLines+hidden != len(test.lines) {
+				t.Errorf("%d lines shown and %d counted, want %d in total", shownLines, hidden, len(test.lines))
+			}
+			if test.wantShown >= 0 && shownLines != test.wantShown {
+				t.Errorf("%d lines shown, want %d", shownLines, test.wantShown)
+			}
+		})
+	}
+}
+
+func TestCreateSummaryMessageCounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		report SummaryReport
+		want   string
+	}{
+		{"counted", SummaryReport{Period: 24 * time.Hour, FiredCount: 3, ResolvedCount: 2}, "Fired: 3\nResolved: 2"},
+		{"history disabled", SummaryReport{Period: 24 * time.Hour, CountsUnavailable: true},
+			"Fired and resolved alerts are only counted with the history enabled"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			message := createSummaryMessage(test.report, config.Config{})
+			fields := message.Embeds[0].Fields
+			if last := fields[len(fields)-1]; last.Value != test.want {
+				t.Errorf("Counts field = %q, want %q", last.Value, test.want)
+			}
+		})
+	}
+}
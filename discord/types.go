@@ -1,5 +1,10 @@
 package discord
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // WebhookParams defines the message body expected by Discord's API
 type WebhookParams struct {
 	Content   string         `json:"content,omitempty"`
@@ -10,18 +15,54 @@ type WebhookParams struct {
 
 // MessageEmbed contains some of the available fields in Discord Embeds
 type MessageEmbed struct {
-	DashbURL     string `json:"dashb_url,omitempty"`
-	GeneratorURL string `json:"generator_url,omitempty"`
-	Title        string `json:"title,omitempty"`
-	Description  string `json:"description,omitempty"`
-	Timestamp    string `json:"timestamp,omitempty"`
-	Color        int    `json:"color,omitempty"`
+	DashbURL     string       `json:"dashb_url,omitempty"`
+	GeneratorURL string       `json:"generator_url,omitempty"`
+	Title        string       `json:"title,omitempty"`
+	Description  string       `json:"description,omitempty"`
+	Timestamp    string       `json:"timestamp,omitempty"`
+	Color        int          `json:"color,omitempty"`
+	Image        *EmbedImage  `json:"image,omitempty"`
+	Fields       []EmbedField `json:"fields,omitempty"`
+}
+
+// EmbedField is a name/value pair shown in the Embed
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// EmbedImage is the image shown at the bottom of an Embed. Uploaded files
+// are referenced as "attachment://<filename>"
+type EmbedImage struct {
+	URL string `json:"url"`
 }
 
+// File is a file uploaded alongside the message as multipart/form-data
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// EmbedQueueItem holds an embed and the attributes of its group used to
+// order it
 type EmbedQueueItem struct {
 	Embed    MessageEmbed `json:"messageEmbed,omitempty"`
 	Priority int          `json:"priority"`
+	GroupKey string       `json:"groupKey"`
+	Title    string       `json:"title"`
+	Count    int          `json:"count"`
+	StartsAt time.Time    `json:"startsAt"`
 }
 
 // A EmbedQueue holds embeds to be ordered before being sent to discord.
 type EmbedQueue []EmbedQueueItem
+
+// DeliveryResult is Discord's answer to a posted message. Messages are posted
+// with wait=true, so Discord returns the created message and its ID
+type DeliveryResult struct {
+	StatusCode int             `json:"statusCode,omitempty"`
+	MessageID  string          `json:"messageId,omitempty"`
+	Response   json.RawMessage `json:"response,omitempty"`
+}
@@ -0,0 +1,166 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+// Actions of the filters
+const (
+	ActionInclude       = "include"
+	ActionExclude       = "exclude"
+	ActionDropWhenAlone = "dropWhenAlone"
+)
+
+// Filter is a parsed AlertFilter
+type Filter struct {
+	Action   string
+	Matchers []*Matcher
+}
+
+// Matches tells whether the alert matches all the matchers
+func (f Filter) Matches(alert alertmanager.Alert) bool {
+	for _, matcher := range f.Matchers {
+		if !matcher.Matches(alert) {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse validates the filter
+func Parse(alertFilter config.AlertFilter) (Filter, error) {
+	switch alertFilter.Action {
+	case ActionInclude, ActionExclude, ActionDropWhenAlone:
+	default:
+		return Filter{}, fmt.Errorf("filter.Parse: Invalid action %q", alertFilter.Action)
+	}
+
+	if len(alertFilter.Matchers) == 0 {
+		return Filter{}, fmt.Errorf("filter.Parse: The %s filter has no matchers", alertFilter.Action)
+	}
+
+	filter := Filter{Action: alertFilter.Action}
+	for _, matcher := range alertFilter.Matchers {
+		parsed, err := ParseMatcher(matcher)
+		if err != nil {
+			return Filter{}, err
+		}
+		filter.Matchers = append(filter.Matchers, parsed)
+	}
+
+	return filter, nil
+}
+
+// Set is the filters of a channel
+type Set struct {
+	include   []Filter
+	exclude   []Filter
+	whenAlone []Filter
+}
+
+// NewSet parses the filters of the channel. Its severitiesToIgnoreWhenAlone,
+// or the global ones, are turned into a dropWhenAlone filter on the effective
+// severity
+func NewSet(discordChannel config.DiscordChannel, configs config.Config) (*Set, error) {
+	set := &Set{}
+
+	for i, alertFilter := range discordChannel.Filters {
+		filter, err := Parse(alertFilter)
+		if err != nil {
+			return nil, fmt.Errorf("filter.NewSet: Filter %d: \n%+v", i, err)
+		}
+
+		switch filter.Action {
+		case ActionInclude:
+			set.include = append(set.include, filter)
+		case ActionExclude:
+			set.exclude = append(set.exclude, filter)
+		case ActionDropWhenAlone:
+			set.whenAlone = append(set.whenAlone, filter)
+		}
+	}
+
+	severitiesToIgnore := discordChannel.SeveritiesToIgnoreWhenAlone
+	if len(severitiesToIgnore) == 0 {
+		severitiesToIgnore = configs.SeveritiesToIgnoreWhenAlone
+	}
+	if len(severitiesToIgnore) > 0 {
+		set.whenAlone = append(set.whenAlone, severityFilter(configs.Severity.Label, severitiesToIgnore))
+	}
+
+	return set, nil
+}
+
+// severityFilter drops the messages with only the given severities when
+// alone. It matches the effective severity, so it's evaluated once the alerts
+// are extracted
+func severityFilter(severityLabel string, severities []string) Filter {
+	quoted := make([]string, len(severities))
+	for i, severity := range severities {
+		quoted[i] = regexp.QuoteMeta(severity)
+	}
+
+	pattern := strings.Join(quoted, "|")
+	return Filter{
+		Action: ActionDropWhenAlone,
+		Matchers: []*Matcher{{
+			Name:     severityLabel,
+			Type:     MatchRegexp,
+			Value:    pattern,
+			severity: true,
+			regexp:   regexp.MustCompile("^(?:" + pattern + ")$"),
+		}},
+	}
+}
+
+// Apply returns the alerts kept by the include and exclude filters, and how
+// many were dropped. With include filters, only the alerts matching one of
+// them are kept. The alerts matching an exclude filter are dropped
+func (s *Set) Apply(alerts []alertmanager.Alert) ([]alertmanager.Alert, int) {
+	if len(s.include) == 0 && len(s.exclude) == 0 {
+		return alerts, 0
+	}
+
+	kept := make([]alertmanager.Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if len(s.include) > 0 && !matchesAny(s.include, alert) {
+			continue
+		}
+		if matchesAny(s.exclude, alert) {
+			continue
+		}
+		kept = append(kept, alert)
+	}
+
+	return kept, len(alerts) - len(kept)
+}
+
+// DropWhenAlone tells whether the message should be dropped, because each of
+// its alerts matches a dropWhenAlone filter. A message without alerts is
+// never dropped
+func (s *Set) DropWhenAlone(alerts []alertmanager.Alert) bool {
+	if len(s.whenAlone) == 0 || len(alerts) == 0 {
+		return false
+	}
+
+	for _, alert := range alerts {
+		if !matchesAny(s.whenAlone, alert) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(filters []Filter, alert alertmanager.Alert) bool {
+	for _, filter := range filters {
+		if filter.Matches(alert) {
+			return true
+		}
+	}
+	return false
+}
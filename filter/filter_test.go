@@ -0,0 +1,113 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+func TestSetApply(t *testing.T) {
+	alert := func(namespace string) alertmanager.Alert {
+		return alertmanager.Alert{Labels: map[string]string{"namespace": namespace}}
+	}
+	alerts := []alertmanager.Alert{alert("prod"), alert("staging"), alert("dev")}
+
+	tests := []struct {
+		name        string
+		filters     []config.AlertFilter
+		wantKept    int
+		wantDropped int
+	}{
+		{"no filters", nil, 3, 0},
+		{"include", []config.AlertFilter{
+			{Action: ActionInclude, Matchers: []string{`namespace="prod"`}},
+		}, 1, 2},
+		{"several includes", []config.AlertFilter{
+			{Action: ActionInclude, Matchers: []string{`namespace="prod"`}},
+			{Action: ActionInclude, Matchers: []string{`namespace="dev"`}},
+		}, 2, 1},
+		{"exclude", []config.AlertFilter{
+			{Action: ActionExclude, Matchers: []string{`namespace=~"staging|dev"`}},
+		}, 1, 2},
+		{"exclude after include", []config.AlertFilter{
+			{Action: ActionInclude, Matchers: []string{`namespace!="prod"`}},
+			{Action: ActionExclude, Matchers: []string{`namespace="dev"`}},
+		}, 1, 2},
+		{"all matchers of a filter", []config.AlertFilter{
+			{Action: ActionExclude, Matchers: []string{`namespace="dev"`, `team="payments"`}},
+		}, 3, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set, err := NewSet(config.DiscordChannel{Filters: test.filters}, config.Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			kept, dropped := set.Apply(alerts)
+			if len(kept) != test.wantKept || dropped != test.wantDropped {
+				t.Errorf("Apply() kept %d and dropped %d, want %d and %d", len(kept), dropped, test.wantKept, test.wantDropped)
+			}
+		})
+	}
+}
+
+func TestSetDropWhenAlone(t *testing.T) {
+	// The severity label is raw, the effective severity is normalized
+	alert := func(severity string) alertmanager.Alert {
+		return alertmanager.Alert{Labels: map[string]string{"severity": "P-" + severity}, Severity: severity}
+	}
+	configs := config.Config{SeveritiesToIgnoreWhenAlone: []string{"info", "warning"}}
+	configs.Severity.Label = "severity"
+
+	tests := []struct {
+		name    string
+		channel config.DiscordChannel
+		alerts  []alertmanager.Alert
+		want    bool
+	}{
+		{"only ignored severities", config.DiscordChannel{}, []alertmanager.Alert{alert("info"), alert("warning")}, true},
+		{"with another severity", config.DiscordChannel{}, []alertmanager.Alert{alert("info"), alert("critical")}, false},
+		{"no alerts", config.DiscordChannel{}, nil, false},
+		{"channel severities", config.DiscordChannel{SeveritiesToIgnoreWhenAlone: []string{"critical"}},
+			[]alertmanager.Alert{alert("info")}, false},
+		{"dropWhenAlone filter", config.DiscordChannel{
+			SeveritiesToIgnoreWhenAlone: []string{"none"},
+			Filters: []config.AlertFilter{
+				{Action: ActionDropWhenAlone, Matchers: []string{`alertname="Watchdog"`}},
+			},
+		}, []alertmanager.Alert{{Labels: map[string]string{"alertname": "Watchdog"}, Severity: "critical"}}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			set, err := NewSet(test.channel, configs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := set.DropWhenAlone(test.alerts); got != test.want {
+				t.Errorf("DropWhenAlone() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalidFilters(t *testing.T) {
+	tests := []struct {
+		name        string
+		alertFilter config.AlertFilter
+	}{
+		{"invalid action", config.AlertFilter{Action: "drop", Matchers: []string{`namespace="dev"`}}},
+		{"no matchers", config.AlertFilter{Action: ActionExclude}},
+		{"invalid matcher", config.AlertFilter{Action: ActionExclude, Matchers: []string{`namespace`}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse(test.alertFilter); err == nil {
+				t.Errorf("Parse() succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+)
+
+// annotationPrefix marks the matchers on annotations instead of labels
+const annotationPrefix = "annotations."
+
+// Types of matchers, as in Prometheus
+const (
+	MatchEqual     = "="
+	MatchNotEqual  = "!="
+	MatchRegexp    = "=~"
+	MatchNotRegexp = "!~"
+)
+
+var matcherPattern = regexp.MustCompile(
+	`^\s*((?:` + regexp.QuoteMeta(annotationPrefix) + `)?[a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*(.*?)\s*$`)
+
+// Matcher matches a label or annotation value, e.g. namespace!~"staging|dev".
+// Missing labels and annotations have an empty value
+type Matcher struct {
+	Name  string
+	Type  string
+	Value string
+
+	annotation bool
+	// severity matches the effective severity of the alert instead of a
+	// label, see alertmanager.Alert
+	severity bool
+	regexp   *regexp.Regexp
+}
+
+// ParseMatcher parses a Prometheus-style matcher. The value can be quoted,
+// and regular expressions are anchored at both ends
+func ParseMatcher(matcher string) (*Matcher, error) {
+	parts := matcherPattern.FindStringSubmatch(matcher)
+	if parts == nil {
+		return nil, fmt.Errorf("filter.ParseMatcher: Invalid matcher %q, expected e.g. name=\"value\"", matcher)
+	}
+
+	value := parts[3]
+	if strings.HasPrefix(value, `"`) {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("filter.ParseMatcher: Invalid value in %q \n%+v", matcher, err)
+		}
+		value = unquoted
+	}
+
+	parsed := &Matcher{
+		Name:       strings.TrimPrefix(parts[1], annotationPrefix),
+		Type:       parts[2],
+		Value:      value,
+		annotation: strings.HasPrefix(parts[1], annotationPrefix),
+	}
+
+	if parsed.Type == MatchRegexp || parsed.Type == MatchNotRegexp {
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("filter.ParseMatcher: Invalid regular expression in %q \n%+v", matcher, err)
+		}
+		parsed.regexp = re
+	}
+
+	return parsed, nil
+}
+
+// Matches tells whether the alert's label or annotation matches
+func (m *Matcher) Matches(alert alertmanager.Alert) bool {
+	value := alert.Labels[m.Name]
+	switch {
+	case m.annotation:
+		value = alert.Annotations[m.Name]
+	case m.severity:
+		value = alert.Severity
+	}
+
+	switch m.Type {
+	case MatchEqual:
+		return value == m.Value
+	case MatchNotEqual:
+		return value != m.Value
+	case MatchRegexp:
+		return m.regexp.MatchString(value)
+	default:
+		return !m.regexp.MatchString(value)
+	}
+}
+
+func (m *Matcher) String() string {
+	name := m.Name
+	if m.annotation {
+		name = annotationPrefix + name
+	}
+	return fmt.Sprintf("%s%s%q", name, m.Type, m.Value)
+}
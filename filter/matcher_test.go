@@ -0,0 +1,110 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+)
+
+func TestParseMatcher(t *testing.T) {
+	tests := []struct {
+		matcher    string
+		wantName   string
+		wantType   string
+		wantValue  string
+		annotation bool
+		wantErr    bool
+	}{
+		{`namespace="prod"`, "namespace", MatchEqual, "prod", false, false},
+		{`namespace=prod`, "namespace", MatchEqual, "prod", false, false},
+		{` namespace != "prod" `, "namespace", MatchNotEqual, "prod", false, false},
+		{`namespace=~"staging|dev"`, "namespace", MatchRegexp, "staging|dev", false, false},
+		{`namespace!~"staging|dev"`, "namespace", MatchNotRegexp, "staging|dev", false, false},
+		{`summary="say \"hi\""`, "summary", MatchEqual, `say "hi"`, false, false},
+		{`team=""`, "team", MatchEqual, "", false, false},
+		{`annotations.runbook!=""`, "runbook", MatchNotEqual, "", true, false},
+		{`_private="1"`, "_private", MatchEqual, "1", false, false},
+		{`namespace`, "", "", "", false, true},
+		{`="prod"`, "", "", "", false, true},
+		{`1namespace="prod"`, "", "", "", false, true},
+		{`name-space="prod"`, "", "", "", false, true},
+		{`labels.namespace="prod"`, "", "", "", false, true},
+		{`namespace="prod`, "", "", "", false, true},
+		{`namespace=~"(prod"`, "", "", "", false, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.matcher, func(t *testing.T) {
+			matcher, err := ParseMatcher(test.matcher)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseMatcher() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if matcher.Name != test.wantName || matcher.Type != test.wantType || matcher.Value != test.wantValue {
+				t.Errorf("ParseMatcher() = %s %s %q, want %s %s %q",
+					matcher.Name, matcher.Type, matcher.Value, test.wantName, test.wantType, test.wantValue)
+			}
+			if matcher.annotation != test.annotation {
+				t.Errorf("annotation = %v, want %v", matcher.annotation, test.annotation)
+			}
+		})
+	}
+}
+
+func TestMatcherMatches(t *testing.T) {
+	alert := alertmanager.Alert{
+		Labels:      map[string]string{"namespace": "staging", "severity": "CRIT"},
+		Annotations: map[string]string{"runbook": "https://runbooks.example.com/latency"},
+		Severity:    "critical",
+	}
+
+	tests := []struct {
+		matcher string
+		want    bool
+	}{
+		{`namespace="staging"`, true},
+		{`namespace="prod"`, false},
+		{`namespace!="prod"`, true},
+		{`namespace!="staging"`, false},
+		{`namespace=~"staging|dev"`, true},
+		{`namespace=~"stag"`, false},
+		{`namespace=~"stag.*"`, true},
+		{`namespace!~"staging|dev"`, false},
+		{`namespace!~"prod"`, true},
+		{`team=""`, true},
+		{`team!=""`, false},
+		{`team=~".*"`, true},
+		{`team!~".+"`, true},
+		{`severity="CRIT"`, true},
+		{`annotations.runbook!=""`, true},
+		{`annotations.runbook=~"https://.*"`, true},
+		{`annotations.namespace="staging"`, false},
+		{`annotations.dashboard=""`, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.matcher, func(t *testing.T) {
+			matcher, err := ParseMatcher(test.matcher)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := matcher.Matches(alert); got != test.want {
+				t.Errorf("Matches() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatcherString(t *testing.T) {
+	for _, input := range []string{`namespace="prod"`, `namespace!~"staging|dev"`, `annotations.runbook!=""`} {
+		matcher, err := ParseMatcher(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := matcher.String(); got != input {
+			t.Errorf("String() = %s, want %s", got, input)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+// Sets are the filters by channel name
+type Sets map[string]*Set
+
+// Default are the Sets used by the application, replaced on startup by the
+// configured ones
+var Default = Sets{}
+
+// NewSetsFromConfig parses the filters of every channel
+func NewSetsFromConfig(configs config.Config) (Sets, error) {
+	sets := Sets{}
+
+	for channelName, discordChannel := range configs.DiscordChannels {
+		set, err := NewSet(discordChannel, configs)
+		if err != nil {
+			return nil, fmt.Errorf("filter.NewSetsFromConfig: Channel %s: \n%+v", channelName, err)
+		}
+		sets[channelName] = set
+	}
+
+	return sets, nil
+}
+
+// Get returns the filters of the channel, parsing them when they aren't in
+// the Sets
+func (s Sets) Get(channelName string, configs config.Config) (*Set, error) {
+	if set, ok := s[channelName]; ok {
+		return set, nil
+	}
+
+	set, err := NewSet(configs.DiscordChannels[channelName], configs)
+	if err != nil {
+		return nil, fmt.Errorf("filter.Sets.Get: Channel %s: \n%+v", channelName, err)
+	}
+	return set, nil
+}
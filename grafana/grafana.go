@@ -0,0 +1,140 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// defaultTimeout bounds the renders when no timeout is configured, as a slow
+// Grafana would otherwise hold the delivery of the message
+const defaultTimeout = 5 * time.Second
+
+// Client renders Grafana panels as PNG images through the render endpoint
+type Client struct {
+	BaseURL         string
+	APIToken        string
+	Width, Height   int
+	TimeRangeBefore time.Duration
+	TimeRangeAfter  time.Duration
+	HTTPClient      *http.Client
+}
+
+// NewClient builds a Client from the GrafanaImage config
+func NewClient(imageConfig config.GrafanaImageConfig) (*Client, error) {
+	before, err := parseOptionalDuration(imageConfig.TimeRangeBefore)
+	if err != nil {
+		return nil, fmt.Errorf("grafana.NewClient: Invalid timeRangeBefore \n%+v", err)
+	}
+
+	after, err := parseOptionalDuration(imageConfig.TimeRangeAfter)
+	if err != nil {
+		return nil, fmt.Errorf("grafana.NewClient: Invalid timeRangeAfter \n%+v", err)
+	}
+
+	timeout, err := parseOptionalDuration(imageConfig.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("grafana.NewClient: Invalid timeout \n%+v", err)
+	}
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+
+	apiToken, err := imageConfig.GetAPIToken()
+	if err != nil {
+		return nil, fmt.Errorf("grafana.NewClient: Error reading apiToken \n%+v", err)
+	}
+
+	return &Client{
+		BaseURL:         strings.TrimSuffix(imageConfig.URL, "/"),
+		APIToken:        apiToken,
+		Width:           imageConfig.Width,
+		Height:          imageConfig.Height,
+		TimeRangeBefore: before,
+		TimeRangeAfter:  after,
+		HTTPClient:      &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// RenderPanel fetches the PNG of a dashboard panel for a time range around
+// startsAt. The trace context is propagated to Grafana
+func (c *Client) RenderPanel(ctx context.Context, dashboardUID, panelID string, startsAt time.Time) (image []byte, err error) {
+	ctx, span := tracing.Start(ctx, "grafana.RenderPanel",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("grafana.dashboard_uid", dashboardUID),
+			attribute.String("grafana.panel_id", panelID)))
+	defer func() { tracing.End(span, err) }()
+
+	from := startsAt.Add(-c.TimeRangeBefore)
+	to := time.Now()
+	if c.TimeRangeAfter > 0 {
+		to = startsAt.Add(c.TimeRangeAfter)
+	}
+
+	query := url.Values{}
+	query.Set("panelId", panelID)
+	query.Set("width", strconv.Itoa(c.Width))
+	query.Set("height", strconv.Itoa(c.Height))
+	query.Set("from", strconv.FormatInt(from.UnixNano()/int64(time.Millisecond), 10))
+	query.Set("to", strconv.FormatInt(to.UnixNano()/int64(time.Millisecond), 10))
+	query.Set("tz", "UTC")
+
+	renderURL := fmt.Sprintf("%s/render/d-solo/%s?%s",
+		c.BaseURL, url.PathEscape(dashboardUID), query.Encode())
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, renderURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("grafana.RenderPanel: Error creating request \n%+v", err)
+	}
+	if c.APIToken != "" {
+		request.Header.Set("Authorization", "Bearer "+c.APIToken)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+
+	r, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("grafana.RenderPanel: Error requesting render \n%+v", err)
+	}
+
+	defer r.Body.Close()
+
+	span.SetAttributes(attribute.Int("http.response.status_code", r.StatusCode))
+
+	contents, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("grafana.RenderPanel: Error reading response body \n%+v", err)
+	}
+
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"grafana.RenderPanel: Problem with render, status code is not 200. StatusCode: %d",
+			r.StatusCode)
+	}
+
+	if contentType := r.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "image/png") {
+		return nil, fmt.Errorf(
+			"grafana.RenderPanel: Unexpected Content-Type %q, expected image/png", contentType)
+	}
+
+	return contents, nil
+}
+
+func parseOptionalDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/redact"
+)
+
+// webhookInfo is part of the webhook object returned by Discord
+type webhookInfo struct {
+	Name      string `json:"name"`
+	ChannelID string `json:"channel_id"`
+}
+
+// StartWebhookChecks periodically verifies that the webhook of every channel
+// is valid, until ctx is done. Discord returns the webhook's metadata on a GET
+// to its URL, so nothing is posted in the channels
+func StartWebhookChecks(ctx context.Context, tracker *Tracker, configs config.Config) error {
+	interval, err := time.ParseDuration(configs.WebhookCheck.Interval)
+	if err != nil {
+		return fmt.Errorf("health.StartWebhookChecks: Invalid interval \n%+v", err)
+	}
+
+	timeout, err := time.ParseDuration(configs.WebhookCheck.Timeout)
+	if err != nil {
+		return fmt.Errorf("health.StartWebhookChecks: Invalid timeout \n%+v", err)
+	}
+
+	client := &http.Client{Timeout: timeout}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			checkWebhooks(ctx, client, tracker, configs)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
+
+func checkWebhooks(ctx context.Context, client *http.Client, tracker *Tracker, configs config.Config) {
+	channelNames := make([]string, 0, len(configs.DiscordChannels))
+	for channelName := range configs.DiscordChannels {
+		channelNames = append(channelNames, channelName)
+	}
+	sort.Strings(channelNames)
+
+	for _, channelName := range channelNames {
+		webhookName, err := checkWebhook(ctx, client, configs.DiscordChannels[channelName])
+		if err != nil {
+			err = fmt.Errorf("%s", redact.Error(err))
+			slog.Error("Webhook is not valid", "channel", channelName, "error", err)
+		}
+		tracker.RecordCheck(channelName, webhookName, err)
+	}
+}
+
+func checkWebhook(ctx context.Context, client *http.Client, discordChannel config.DiscordChannel) (string, error) {
+	webhookURL, err := discordChannel.GetWebhookURL()
+	if err != nil {
+		return "", err
+	}
+
+	request, err := http.NewRequest(http.MethodGet, webhookURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("health.checkWebhook: Error creating request \n%+v", err)
+	}
+
+	r, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		return "", fmt.Errorf("health.checkWebhook: Error requesting webhook \n%+v", err)
+	}
+
+	defer r.Body.Close()
+
+	contents, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return "", fmt.Errorf("health.checkWebhook: Error reading response body \n%+v", err)
+	}
+
+	if r.StatusCode != http.StatusOK {
+		return "", fmt.Errorf(
+			"health.checkWebhook: Webhook check failed. StatusCode: %d, Response Body: %s",
+			r.StatusCode, string(contents))
+	}
+
+	var info webhookInfo
+	if err := json.Unmarshal(contents, &info); err != nil {
+		return "", fmt.Errorf("health.checkWebhook: Error parsing webhook \n%+v", err)
+	}
+
+	return info.Name, nil
+}
@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+func TestCheckWebhooks(t *testing.T) {
+	discordServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Expected a GET, got %s", r.Method)
+		}
+		if strings.HasSuffix(r.URL.Path, "/valid-token") {
+			w.Write([]byte(`{"name": "Alerts", "channel_id": "1"}`))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message": "Invalid Webhook Token", "code": 50027}`))
+	}))
+	defer discordServer.Close()
+
+	configs := config.Config{DiscordChannels: map[string]config.DiscordChannel{
+		"valid":   {WebhookURL: config.WebhookURL(discordServer.URL + "/api/webhooks/1/valid-token")},
+		"invalid": {WebhookURL: config.WebhookURL(discordServer.URL + "/api/webhooks/2/invalid-token")},
+		"down":    {WebhookURL: config.WebhookURL("http://127.0.0.1:1/api/webhooks/3/down-token")},
+	}}
+
+	tracker := NewTracker()
+	checkWebhooks(context.Background(), &http.Client{Timeout: time.Second}, tracker, configs)
+
+	statuses := map[string]ChannelStatus{}
+	for _, status := range tracker.Channels() {
+		statuses[status.Name] = status
+	}
+
+	if valid := statuses["valid"]; valid.WebhookValid == nil || !*valid.WebhookValid || valid.WebhookName != "Alerts" {
+		t.Errorf("Expected a valid webhook named Alerts, got %+v", valid)
+	}
+
+	for _, name := range []string{"invalid", "down"} {
+		status := statuses[name]
+		if status.WebhookValid == nil || *status.WebhookValid || status.LastCheck == nil {
+			t.Errorf("Expected the %s webhook to be checked and invalid, got %+v", name, status)
+		}
+		if status.LastCheckError == "" || strings.Contains(status.LastCheckError, name+"-token") {
+			t.Errorf("Expected a redacted error for %s, got %q", name, status.LastCheckError)
+		}
+	}
+
+	if invalid := tracker.InvalidWebhooks(); len(invalid) != 2 {
+		t.Errorf("InvalidWebhooks() = %v, want down and invalid", invalid)
+	}
+}
@@ -0,0 +1,142 @@
+package health
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ChannelStatus is the health of a Discord channel, as seen by deliveries and
+// webhook checks
+type ChannelStatus struct {
+	Name string `json:"name"`
+	// WebhookValid is unset until the webhook has been checked
+	WebhookValid   *bool      `json:"webhookValid,omitempty"`
+	WebhookName    string     `json:"webhookName,omitempty"`
+	LastCheck      *time.Time `json:"lastCheck,omitempty"`
+	LastCheckError string     `json:"lastCheckError,omitempty"`
+	LastDelivery   *time.Time `json:"lastSuccessfulDelivery,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	LastErrorTime  *time.Time `json:"lastErrorTime,omitempty"`
+}
+
+// Tracker keeps the status of every channel and whether the application is
+// ready to receive alerts
+type Tracker struct {
+	mutex    sync.RWMutex
+	ready    bool
+	draining bool
+	channels map[string]*ChannelStatus
+}
+
+// NewTracker creates an empty, not ready, Tracker
+func NewTracker() *Tracker {
+	return &Tracker{channels: map[string]*ChannelStatus{}}
+}
+
+// Default is the Tracker used by the application
+var Default = NewTracker()
+
+// SetReady marks the application as ready, or not, to receive alerts
+func (t *Tracker) SetReady(ready bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.ready = ready
+}
+
+// Ready tells if the application is ready to receive alerts
+func (t *Tracker) Ready() bool {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.ready && !t.draining
+}
+
+// StartDraining marks the application as shutting down. It stops being ready
+// and new alerts are refused, while the deliveries in progress complete
+func (t *Tracker) StartDraining() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.draining = true
+}
+
+// Draining tells if the application is shutting down
+func (t *Tracker) Draining() bool {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.draining
+}
+
+// AddChannel registers a configured channel, so it's listed before any
+// delivery or check
+func (t *Tracker) AddChannel(name string) {
+	t.update(name, func(*ChannelStatus) {})
+}
+
+// RecordDelivery records a delivery to the channel. err is nil on success
+// and its message must already be redacted
+func (t *Tracker) RecordDelivery(name string, err error) {
+	now := time.Now()
+	t.update(name, func(status *ChannelStatus) {
+		if err == nil {
+			status.LastDelivery = &now
+			return
+		}
+		status.LastError = err.Error()
+		status.LastErrorTime = &now
+	})
+}
+
+// RecordCheck records the result of a webhook check. err is nil when the
+// webhook is valid and its message must already be redacted
+func (t *Tracker) RecordCheck(name string, webhookName string, err error) {
+	now := time.Now()
+	valid := err == nil
+	t.update(name, func(status *ChannelStatus) {
+		status.LastCheck = &now
+		status.WebhookValid = &valid
+		status.WebhookName = webhookName
+		status.LastCheckError = ""
+		if err != nil {
+			status.LastCheckError = err.Error()
+		}
+	})
+}
+
+// Channels returns a copy of the status of every channel, ordered by name
+func (t *Tracker) Channels() []ChannelStatus {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	channels := make([]ChannelStatus, 0, len(t.channels))
+	for _, status := range t.channels {
+		channels = append(channels, *status)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].Name < channels[j].Name
+	})
+
+	return channels
+}
+
+// InvalidWebhooks returns the channels whose last webhook check failed
+func (t *Tracker) InvalidWebhooks() []string {
+	invalid := []string{}
+	for _, status := range t.Channels() {
+		if status.WebhookValid != nil && !*status.WebhookValid {
+			invalid = append(invalid, status.Name)
+		}
+	}
+	return invalid
+}
+
+func (t *Tracker) update(name string, update func(*ChannelStatus)) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	status, ok := t.channels[name]
+	if !ok {
+		status = &ChannelStatus{Name: name}
+		t.channels[name] = status
+	}
+	update(status)
+}
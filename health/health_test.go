@@ -0,0 +1,89 @@
+package health
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReadiness(t *testing.T) {
+	tracker := NewTracker()
+	if tracker.Ready() {
+		t.Errorf("Expected a new tracker not to be ready")
+	}
+
+	tracker.SetReady(true)
+	if !tracker.Ready() || tracker.Draining() {
+		t.Errorf("Expected the tracker to be ready and not draining")
+	}
+
+	tracker.StartDraining()
+	if tracker.Ready() || !tracker.Draining() {
+		t.Errorf("Expected a draining tracker not to be ready")
+	}
+
+	tracker.SetReady(true)
+	if tracker.Ready() {
+		t.Errorf("Expected a draining tracker to stay not ready")
+	}
+}
+
+func TestRecordDelivery(t *testing.T) {
+	tracker := NewTracker()
+	tracker.AddChannel("prod")
+	tracker.AddChannel("default")
+
+	tracker.RecordDelivery("prod", errors.New("status code 400"))
+	tracker.RecordDelivery("prod", nil)
+	tracker.RecordDelivery("staging", nil)
+
+	channels := tracker.Channels()
+	names := []string{}
+	for _, status := range channels {
+		names = append(names, status.Name)
+	}
+	if want := []string{"default", "prod", "staging"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("Channels() = %v, want %v", names, want)
+	}
+
+	if channels[0].LastDelivery != nil || channels[0].LastError != "" {
+		t.Errorf("Expected no delivery for the default channel, got %+v", channels[0])
+	}
+	// A success doesn't clear the last error, which tells when it happened
+	prod := channels[1]
+	if prod.LastDelivery == nil || prod.LastError != "status code 400" || prod.LastErrorTime == nil {
+		t.Errorf("Expected a delivery and the last error for prod, got %+v", prod)
+	}
+	if channels[2].LastDelivery == nil {
+		t.Errorf("Expected a delivery for staging, got %+v", channels[2])
+	}
+}
+
+func TestInvalidWebhooks(t *testing.T) {
+	tracker := NewTracker()
+	tracker.AddChannel("unchecked")
+	tracker.RecordCheck("valid", "Alerts", nil)
+	tracker.RecordCheck("invalid", "", errors.New("status code 401"))
+	tracker.RecordCheck("fixed", "", errors.New("status code 404"))
+	tracker.RecordCheck("fixed", "Alerts", nil)
+
+	if invalid := tracker.InvalidWebhooks(); !reflect.DeepEqual(invalid, []string{"invalid"}) {
+		t.Errorf("InvalidWebhooks() = %v, want [invalid]", invalid)
+	}
+
+	for _, status := range tracker.Channels() {
+		if status.Name == "fixed" && (status.LastCheckError != "" || status.WebhookName != "Alerts") {
+			t.Errorf("Expected the last check to replace the previous one, got %+v", status)
+		}
+	}
+}
+
+func TestChannelsReturnsCopies(t *testing.T) {
+	tracker := NewTracker()
+	tracker.RecordDelivery("prod", errors.New("status code 400"))
+
+	tracker.Channels()[0].LastError = ""
+	if tracker.Channels()[0].LastError == "" {
+		t.Errorf("Expected Channels() to return copies")
+	}
+}
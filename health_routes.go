@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/health"
+)
+
+// registerHealthRoutes adds the liveness, readiness and status endpoints
+func registerHealthRoutes(router *gin.Engine, tracker *health.Tracker, configs config.Config) {
+	router.GET("/-/healthy", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
+	})
+
+	router.GET("/-/ready", func(c *gin.Context) {
+		if !tracker.Ready() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready"})
+			return
+		}
+
+		if configs.WebhookCheck.AffectsReadiness {
+			if invalid := tracker.InvalidWebhooks(); len(invalid) > 0 {
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"status":          "not ready",
+					"invalidWebhooks": invalid,
+				})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	})
+
+	router.GET("/api/status", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"ready":    tracker.Ready(),
+			"draining": tracker.Draining(),
+			"channels": tracker.Channels(),
+		})
+	})
+}
@@ -0,0 +1,167 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore keeps the latest entries in memory and appends every entry to a
+// JSON lines file, so the history survives restarts. The file is rewritten
+// without the entries beyond capacity or retention once it holds twice the
+// capacity
+type FileStore struct {
+	mutex     sync.Mutex
+	memory    *MemoryStore
+	path      string
+	file      *os.File
+	lines     int
+	capacity  int
+	retention time.Duration
+}
+
+// OpenFileStore loads the entries of the file at path, creating it if needed
+func OpenFileStore(path string, capacity int, retention time.Duration) (*FileStore, error) {
+	store := &FileStore{
+		memory:    NewMemoryStore(capacity, retention),
+		path:      path,
+		capacity:  capacity,
+		retention: retention,
+	}
+
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+
+	if err := store.compact(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Add records an entry in memory and on disk
+func (s *FileStore) Add(entry Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.memory.Add(entry)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("history.FileStore.Add: Error marshaling entry \n%+v", err)
+	}
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("history.FileStore.Add: Error writing entry \n%+v", err)
+	}
+	s.lines++
+
+	if s.lines >= 2*s.capacity {
+		return s.compact()
+	}
+
+	return nil
+}
+
+// Query returns the entries matching filter, newest first
+func (s *FileStore) Query(filter Filter) ([]Entry, error) {
+	return s.memory.Query(filter)
+}
+
+// Close closes the file
+func (s *FileStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.file.Close()
+}
+
+func (s *FileStore) load() error {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("history.FileStore.load: Error opening %s \n%+v", s.path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var entry Entry
+		// A line cut by a crash is skipped instead of losing the history
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		s.memory.Add(entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("history.FileStore.load: Error reading %s \n%+v", s.path, err)
+	}
+
+	return nil
+}
+
+// compact rewrites the file with the entries kept in memory and within
+// retention, then reopens it for appending
+func (s *FileStore) compact() error {
+	var oldest time.Time
+	if s.retention > 0 {
+		oldest = time.Now().Add(-s.retention)
+	}
+
+	temporary, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("history.FileStore.compact: Error creating temporary file \n%+v", err)
+	}
+	defer os.Remove(temporary.Name())
+
+	writer := bufio.NewWriter(temporary)
+	lines := 0
+	for _, entry := range s.memory.all() {
+		if entry.Time.Before(oldest) {
+			continue
+		}
+
+		line, err := json.Marshal(entry)
+		if err != nil {
+			temporary.Close()
+			return fmt.Errorf("history.FileStore.compact: Error marshaling entry \n%+v", err)
+		}
+		writer.Write(append(line, '\n'))
+		lines++
+	}
+
+	if err := writer.Flush(); err != nil {
+		temporary.Close()
+		return fmt.Errorf("history.FileStore.compact: Error writing %s \n%+v", temporary.Name(), err)
+	}
+	if err := temporary.Close(); err != nil {
+		return fmt.Errorf("history.FileStore.compact: Error writing %s \n%+v", temporary.Name(), err)
+	}
+
+	// The current file is only closed once replaced and reopened, so entries
+	// are still appended to it when compacting fails
+	if err := os.Rename(temporary.Name(), s.path); err != nil {
+		return fmt.Errorf("history.FileStore.compact: Error replacing %s \n%+v", s.path, err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("history.FileStore.compact: Error opening %s \n%+v", s.path, err)
+	}
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file = file
+	s.lines = lines
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package history
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
+
+func TestFileStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Now()
+
+	store, err := OpenFileStore(path, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if err := store.Add(Entry{Time: now, Channel: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Close()
+
+	// A line cut by a crash is skipped
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"channel": "c", "ti` + "\n")
+	file.Close()
+
+	store, err = OpenFileStore(path, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	entries, err := store.Query(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := channels(entries); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Errorf("Query() = %v, want the saved entries", got)
+	}
+	if lines := countLines(t, path); lines != 2 {
+		t.Errorf("%d lines after opening, want the cut line dropped", lines)
+	}
+}
+
+func TestFileStoreCompacts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Now()
+
+	store, err := OpenFileStore(path, 3, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	store.Add(Entry{Time: now.Add(-2 * time.Hour), Channel: "expired"})
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if err := store.Add(Entry{Time: now, Channel: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The file held 5 lines, under twice the capacity
+	if lines := countLines(t, path); lines != 5 {
+		t.Fatalf("%d lines before compacting, want 5", lines)
+	}
+
+	if err := store.Add(Entry{Time: now, Channel: "e"}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := countLines(t, path); lines != 3 {
+		t.Errorf("%d lines after compacting, want the 3 kept in memory", lines)
+	}
+
+	// Entries are still appended after compacting
+	if err := store.Add(Entry{Time: now, Channel: "f"}); err != nil {
+		t.Fatal(err)
+	}
+	if lines := countLines(t, path); lines != 4 {
+		t.Errorf("%d lines, want 4", lines)
+	}
+}
+
+func TestFileStoreAfterFailedCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	store, err := OpenFileStore(path, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// A non-empty directory in place of the file makes the rename fail
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(path, "blocked"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	store.Add(Entry{Channel: "a"})
+	if err := store.Add(Entry{Channel: "b"}); err == nil {
+		t.Fatal("Expected compacting to fail")
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(Entry{Channel: "c"}); err != nil {
+		t.Fatalf("Expected the store to recover, got %v", err)
+	}
+	if err := store.Add(Entry{Channel: "d"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _ := store.Query(Filter{})
+	if got := channels(entries); !reflect.DeepEqual(got, []string{"d"}) {
+		t.Errorf("Query() = %v, want the latest entry", got)
+	}
+	if lines := countLines(t, path); lines != 1 {
+		t.Errorf("%d lines, want 1", lines)
+	}
+}
@@ -0,0 +1,128 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+// Outcomes of a processed notification
+const (
+	OutcomeSent       = "sent"
+	OutcomeSuppressed = "suppressed"
+	OutcomeFailed     = "failed"
+)
+
+// AlertSummary identifies an alert of a notification
+type AlertSummary struct {
+	Fingerprint string `json:"fingerprint,omitempty"`
+	AlertName   string `json:"alertname"`
+	Status      string `json:"status"`
+	Severity    string `json:"severity,omitempty"`
+}
+
+// Entry records a notification processed for a channel
+type Entry struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"requestId,omitempty"`
+	Channel   string    `json:"channel"`
+	Receiver  string    `json:"receiver,omitempty"`
+	Status    string    `json:"status"`
+	GroupKey  string    `json:"groupKey,omitempty"`
+	// Alerts holds every alert of the payload, TruncatedAlerts the ones
+	// dropped by Alertmanager
+	Alerts          []AlertSummary `json:"alerts"`
+	TruncatedAlerts int            `json:"truncatedAlerts,omitempty"`
+	// Outcome is sent, suppressed or failed
+	Outcome           string          `json:"outcome"`
+	SuppressionReason string          `json:"suppressionReason,omitempty"`
+	Message           json.RawMessage `json:"message,omitempty"`
+	DiscordStatus     int             `json:"discordStatus,omitempty"`
+	MessageID         string          `json:"messageId,omitempty"`
+	Error             string          `json:"error,omitempty"`
+	Duration          time.Duration   `json:"durationNs"`
+}
+
+// Filter selects entries. Empty fields match everything
+type Filter struct {
+	Channel     string
+	AlertName   string
+	Fingerprint string
+	// Status matches entries with at least one alert in that status
+	Status  string
+	Outcome string
+	Since   time.Time
+	Until   time.Time
+	// Limit is the maximum number of entries returned, newest first
+	Limit int
+}
+
+// Store keeps the history of notifications
+type Store interface {
+	Add(entry Entry) error
+	// Query returns the entries matching filter, newest first
+	Query(filter Filter) ([]Entry, error)
+}
+
+// Default is the Store used by the application, replaced on startup by the
+// configured one
+var Default Store = NewMemoryStore(1000, 0)
+
+// Discard drops every entry, used when the history is disabled
+var Discard Store = discardStore{}
+
+type discardStore struct{}
+
+func (discardStore) Add(Entry) error               { return nil }
+func (discardStore) Query(Filter) ([]Entry, error) { return []Entry{}, nil }
+
+// NewStore creates the store defined by the History config
+func NewStore(historyConfig config.HistoryConfig) (Store, error) {
+	var retention time.Duration
+	if historyConfig.Retention != "" {
+		var err error
+		retention, err = time.ParseDuration(historyConfig.Retention)
+		if err != nil {
+			return nil, fmt.Errorf("history.NewStore: Invalid retention \n%+v", err)
+		}
+	}
+
+	switch historyConfig.Storage {
+	case "", "memory":
+		return NewMemoryStore(historyConfig.MaxEntries, retention), nil
+	case "file":
+		return OpenFileStore(historyConfig.Path, historyConfig.MaxEntries, retention)
+	}
+
+	return nil, fmt.Errorf("history.NewStore: Invalid storage %q, should be memory or file", historyConfig.Storage)
+}
+
+// Matches tells if the entry is selected by the filter
+func (f Filter) Matches(entry Entry) bool {
+	switch {
+	case f.Channel != "" && entry.Channel != f.Channel:
+		return false
+	case f.Outcome != "" && entry.Outcome != f.Outcome:
+		return false
+	case !f.Since.IsZero() && entry.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && entry.Time.After(f.Until):
+		return false
+	}
+
+	if f.AlertName == "" && f.Fingerprint == "" && f.Status == "" {
+		return true
+	}
+
+	for _, alert := range entry.Alerts {
+		if (f.AlertName == "" || alert.AlertName == f.AlertName) &&
+			(f.Fingerprint == "" || alert.Fingerprint == f.Fingerprint) &&
+			(f.Status == "" || alert.Status == f.Status) {
+			return true
+		}
+	}
+
+	return false
+}
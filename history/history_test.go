@@ -0,0 +1,83 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+func TestFilterMatches(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	entry := Entry{
+		Time:    now,
+		Channel: "prod",
+		Outcome: OutcomeSent,
+		Alerts: []AlertSummary{
+			{Fingerprint: "a1", AlertName: "HighLatency", Status: "firing"},
+			{Fingerprint: "b2", AlertName: "HighErrorRate", Status: "resolved"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"channel", Filter{Channel: "prod"}, true},
+		{"other channel", Filter{Channel: "staging"}, false},
+		{"outcome", Filter{Outcome: OutcomeFailed}, false},
+		{"since", Filter{Since: now}, true},
+		{"since after", Filter{Since: now.Add(time.Second)}, false},
+		{"until", Filter{Until: now}, true},
+		{"until before", Filter{Until: now.Add(-time.Second)}, false},
+		{"alertname", Filter{AlertName: "HighErrorRate"}, true},
+		{"fingerprint", Filter{Fingerprint: "a1"}, true},
+		{"status", Filter{Status: "resolved"}, true},
+		{"missing alertname", Filter{AlertName: "DiskFilling"}, false},
+		{"same alert", Filter{AlertName: "HighLatency", Status: "firing"}, true},
+		{"different alerts", Filter{AlertName: "HighLatency", Status: "resolved"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.filter.Matches(entry); got != test.want {
+				t.Errorf("Matches() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNewStore(t *testing.T) {
+	tests := []struct {
+		name          string
+		historyConfig config.HistoryConfig
+		wantErr       bool
+	}{
+		{"memory by default", config.HistoryConfig{MaxEntries: 10}, false},
+		{"file", config.HistoryConfig{Storage: "file", Path: filepath.Join(t.TempDir(), "history.jsonl")}, false},
+		{"invalid storage", config.HistoryConfig{Storage: "redis"}, true},
+		{"invalid retention", config.HistoryConfig{Retention: "a week"}, true},
+		{"unwritable file", config.HistoryConfig{Storage: "file", Path: filepath.Join(t.TempDir(), "missing", "history.jsonl")}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewStore(test.historyConfig)
+			if (err != nil) != test.wantErr {
+				t.Errorf("NewStore() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	if err := Discard.Add(Entry{Channel: "prod"}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := Discard.Query(Filter{}); err != nil || len(entries) != 0 {
+		t.Errorf("Query() = %v, %v, want no entries", entries, err)
+	}
+}
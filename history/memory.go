@@ -0,0 +1,85 @@
+package history
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps the latest entries in a ring buffer
+type MemoryStore struct {
+	mutex     sync.RWMutex
+	entries   []Entry
+	next      int
+	full      bool
+	retention time.Duration
+}
+
+// NewMemoryStore creates a store holding up to capacity entries. Entries
+// older than retention are not returned, unless retention is 0
+func NewMemoryStore(capacity int, retention time.Duration) *MemoryStore {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &MemoryStore{entries: make([]Entry, capacity), retention: retention}
+}
+
+// Add records an entry, overwriting the oldest one when the store is full
+func (s *MemoryStore) Add(entry Entry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries[s.next] = entry
+	s.next = (s.next + 1) % len(s.entries)
+	if s.next == 0 {
+		s.full = true
+	}
+
+	return nil
+}
+
+// Query returns the entries matching filter, newest first
+func (s *MemoryStore) Query(filter Filter) ([]Entry, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.retention > 0 {
+		if oldest := time.Now().Add(-s.retention); filter.Since.Before(oldest) {
+			filter.Since = oldest
+		}
+	}
+
+	entries := []Entry{}
+	for i := 0; i < s.len(); i++ {
+		// Walk backwards from the newest entry
+		entry := s.entries[(s.next-1-i+len(s.entries))%len(s.entries)]
+		if !filter.Matches(entry) {
+			continue
+		}
+
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) >= filter.Limit {
+			break
+		}
+	}
+
+	return entries, nil
+}
+
+// all returns every entry, oldest first
+func (s *MemoryStore) all() []Entry {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entries := make([]Entry, 0, s.len())
+	for i := s.len(); i > 0; i-- {
+		entries = append(entries, s.entries[(s.next-i+len(s.entries))%len(s.entries)])
+	}
+	return entries
+}
+
+func (s *MemoryStore) len() int {
+	if s.full {
+		return len(s.entries)
+	}
+	return s.next
+}
@@ -0,0 +1,79 @@
+package history
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func channels(entries []Entry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Channel
+	}
+	return names
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore(3, 0)
+
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		store.Add(Entry{Time: now.Add(time.Duration(i) * time.Second), Channel: name})
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"oldest entries overwritten, newest first", Filter{}, []string{"e", "d", "c"}},
+		{"limit", Filter{Limit: 2}, []string{"e", "d"}},
+		{"filter", Filter{Channel: "d"}, []string{"d"}},
+		{"overwritten entry", Filter{Channel: "a"}, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := store.Query(test.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := channels(entries); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Query() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if got := channels(store.all()); !reflect.DeepEqual(got, []string{"c", "d", "e"}) {
+		t.Errorf("all() = %v, want oldest first", got)
+	}
+}
+
+func TestMemoryStoreRetention(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore(10, time.Hour)
+	store.Add(Entry{Time: now.Add(-2 * time.Hour), Channel: "expired"})
+	store.Add(Entry{Time: now.Add(-time.Minute), Channel: "kept"})
+
+	for _, filter := range []Filter{{}, {Since: now.Add(-3 * time.Hour)}} {
+		entries, err := store.Query(filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := channels(entries); !reflect.DeepEqual(got, []string{"kept"}) {
+			t.Errorf("Query(%+v) = %v, want the entries within retention", filter, got)
+		}
+	}
+}
+
+func TestMemoryStoreMinimumCapacity(t *testing.T) {
+	store := NewMemoryStore(0, 0)
+	store.Add(Entry{Channel: "a"})
+	store.Add(Entry{Channel: "b"})
+
+	entries, _ := store.Query(Filter{})
+	if got := channels(entries); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("Query() = %v, want the last entry", got)
+	}
+}
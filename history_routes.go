@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/history"
+)
+
+// defaultHistoryLimit is the number of entries returned when no limit is given
+const defaultHistoryLimit = 100
+
+// registerHistoryRoutes adds the history API, protected by the admin token
+// since the entries hold the full alerts. Entries are filtered by the
+// channel, alertname, fingerprint, status, outcome, since and until query
+// parameters, and returned newest first
+func registerHistoryRoutes(router *gin.Engine, store history.Store, adminConfig config.AdminConfig) {
+	router.GET("/api/history", requireAdminToken(adminConfig), func(c *gin.Context) {
+		filter, err := parseHistoryFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		entries, err := store.Query(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"entries": entries})
+	})
+}
+
+func parseHistoryFilter(c *gin.Context) (history.Filter, error) {
+	filter := history.Filter{
+		Channel:     c.Query("channel"),
+		AlertName:   c.Query("alertname"),
+		Fingerprint: c.Query("fingerprint"),
+		Status:      c.Query("status"),
+		Outcome:     c.Query("outcome"),
+		Limit:       defaultHistoryLimit,
+	}
+
+	var err error
+	if filter.Since, err = parseHistoryTime(c.Query("since")); err != nil {
+		return filter, fmt.Errorf("invalid since: %v", err)
+	}
+	if filter.Until, err = parseHistoryTime(c.Query("until")); err != nil {
+		return filter, fmt.Errorf("invalid until: %v", err)
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 0 {
+			return filter, fmt.Errorf("invalid limit %q", limit)
+		}
+	}
+
+	return filter, nil
+}
+
+// parseHistoryTime accepts RFC 3339 times or durations before now, e.g. "12h"
+func parseHistoryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+type contextKey struct{}
+
+type requestIDKey struct{}
+
+// NewLogger creates a logger writing to output in the configured format,
+// "logfmt" or "json", from the configured level on
+func NewLogger(logConfig config.LoggingConfig, output io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logConfig.Level)); err != nil {
+		return nil, fmt.Errorf("logging.NewLogger: Invalid level %q \n%+v", logConfig.Level, err)
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(logConfig.Format) {
+	case "", "logfmt", "text":
+		return slog.New(slog.NewTextHandler(output, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(output, options)), nil
+	}
+
+	return nil, fmt.Errorf("logging.NewLogger: Invalid format %q, should be logfmt or json", logConfig.Format)
+}
+
+// WithLogger returns a copy of ctx carrying logger, usually one with the
+// request ID and channel as attributes
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request ID carried by ctx, if any
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// NewRequestID returns a random ID correlating the logs of a request
+func NewRequestID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(id)
+}
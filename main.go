@@ -1,41 +1,138 @@
 package main
 
 import (
+	"context"
+	"io"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/kolesaev/alertmanager-discord/alertmanager"
 	"github.com/kolesaev/alertmanager-discord/config"
-	"github.com/kolesaev/alertmanager-discord/discord"
+	"github.com/kolesaev/alertmanager-discord/filter"
+	"github.com/kolesaev/alertmanager-discord/health"
+	"github.com/kolesaev/alertmanager-discord/history"
+	"github.com/kolesaev/alertmanager-discord/logging"
+	"github.com/kolesaev/alertmanager-discord/oncall"
+	"github.com/kolesaev/alertmanager-discord/redact"
+	"github.com/kolesaev/alertmanager-discord/state"
+	"github.com/kolesaev/alertmanager-discord/summary"
+	"github.com/kolesaev/alertmanager-discord/tracing"
+	"github.com/kolesaev/alertmanager-discord/windows"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
-	configs := config.LoadUserConfig()
+	// Every logging path goes through the redaction layer, so webhook tokens
+	// never reach the logs. The standard logger is used until the config is
+	// loaded and the configured one replaces it
+	log.SetOutput(redact.NewWriter(os.Stderr))
+	gin.DefaultWriter = redact.NewWriter(os.Stdout)
+	gin.DefaultErrorWriter = redact.NewWriter(os.Stderr)
 
-	router := gin.Default()
+	if len(os.Args) > 1 && os.Args[1] == "test-channel" {
+		os.Exit(runTestChannel(os.Args[2:]))
+	}
+
+	configs := config.LoadUserConfig(os.Args[1:])
+
+	logger, err := logging.NewLogger(configs.Logging, redact.NewWriter(os.Stderr))
+	if err != nil {
+		fatal(err)
+	}
+	slog.SetDefault(logger)
+
+	config.LogConfig(*configs)
+
+	// gin's debug output, such as the list of routes, is only useful when
+	// debugging
+	if configs.Logging.Level != "debug" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	shutdownTracing, err := tracing.Setup(context.Background(), configs.Tracing)
+	if err != nil {
+		fatal(err)
+	}
+
+	router.Use(gin.Recovery(), requestTracer(), requestLogger(logger), rejectWhileDraining(health.Default))
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"status": "Application is healthy!",
 		})
 	})
 
-	router.POST("/:channel", func(c *gin.Context) {
-		channelName := c.Param("channel")
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
-		var alertmanagerBody alertmanager.MessageBody
-		if err := c.ShouldBindJSON(&alertmanagerBody); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
+	for channelName := range configs.DiscordChannels {
+		health.Default.AddChannel(channelName)
+	}
+	registerHealthRoutes(router, health.Default, *configs)
+
+	windowRegistry, err := windows.NewRegistryFromConfig(*configs)
+	if err != nil {
+		fatal(err)
+	}
+	windows.Default = windowRegistry
+
+	schedules, err := oncall.NewSchedulesFromConfig(*configs)
+	if err != nil {
+		fatal(err)
+	}
+	oncall.Default = schedules
+
+	filterSets, err := filter.NewSetsFromConfig(*configs)
+	if err != nil {
+		fatal(err)
+	}
+	filter.Default = filterSets
+	registerAdminRoutes(router, *configs)
+
+	history.Default = history.Discard
+	if configs.History.Enabled {
+		store, err := history.NewStore(configs.History)
+		if err != nil {
+			fatal(err)
 		}
+		history.Default = store
+		registerHistoryRoutes(router, store, configs.Admin)
+		if token, _ := configs.Admin.GetToken(); token == "" {
+			logger.Warn("The history API answers 503 until admin.token is set")
+		}
+	}
 
-		if err := discord.SendAlerts(channelName, alertmanagerBody, *configs); err != nil {
-			log.Println("[ERROR] ", err)
+	alertState, err := state.NewTrackerFromConfig(configs.AlertState)
+	if err != nil {
+		fatal(err)
+	}
+	state.Default = alertState
+	registerAlertRoutes(router, state.Default, *configs)
+
+	if configs.Dashboard.Enabled {
+		if err := registerDashboardRoutes(router, health.Default, history.Default, state.Default, *configs); err != nil {
+			fatal(err)
+		}
+		if token, _ := configs.Admin.GetToken(); token == "" {
+			logger.Warn("The dashboard answers 503 until admin.token is set")
 		}
+	}
 
-		c.String(http.StatusOK, "Channel: %s", channelName)
-	})
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+
+	if err := summary.Start(backgroundCtx, *configs, state.Default, history.Default); err != nil {
+		fatal(err)
+	}
+
+	if configs.WebhookCheck.Enabled {
+		if err := health.StartWebhookChecks(backgroundCtx, health.Default, *configs); err != nil {
+			fatal(err)
+		}
+	}
+
+	registerWebhookRoute(router, state.Default, *configs)
 
 	s := &http.Server{
 		Addr:           configs.ListenAddress,
@@ -45,6 +142,26 @@ func main() {
 		MaxHeaderBytes: 1 << 20,
 	}
 
-	s.ListenAndServe()
+	stop := func() {
+		stopBackground()
+
+		if closer, ok := history.Default.(io.Closer); ok {
+			closer.Close()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("Error flushing traces", "error", err)
+		}
+	}
+
+	if err := serve(s, health.Default, configs.DrainDelay, configs.ShutdownTimeout, stop); err != nil {
+		fatal(err)
+	}
+}
 
+func fatal(err error) {
+	slog.Error("Exiting", "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "alertmanager_discord"
+
+var (
+	// TruncatedNotifications counts the webhooks received with alerts
+	// truncated by Alertmanager's max_alerts, by channel
+	TruncatedNotifications = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "truncated_notifications_total",
+			Help:      "Number of notifications received with alerts truncated by Alertmanager.",
+		},
+		[]string{"channel"},
+	)
+
+	// TruncatedAlerts counts the alerts dropped by Alertmanager's max_alerts,
+	// by channel
+	TruncatedAlerts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "truncated_alerts_total",
+			Help:      "Number of alerts truncated by Alertmanager before being received.",
+		},
+		[]string{"channel"},
+	)
+
+	// SuppressedNotifications counts the notifications not sent to Discord,
+	// by channel and by what suppressed them
+	SuppressedNotifications = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "suppressed_notifications_total",
+			Help:      "Number of notifications received but not sent to Discord.",
+		},
+		[]string{"channel", "reason"},
+	)
+
+	// SuppressedAlerts counts the alerts left out of the notifications sent,
+	// by channel and by what suppressed them
+	SuppressedAlerts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "suppressed_alerts_total",
+			Help:      "Number of alerts received but left out of the notifications sent to Discord.",
+		},
+		[]string{"channel", "reason"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		TruncatedNotifications,
+		TruncatedAlerts,
+		SuppressedNotifications,
+		SuppressedAlerts,
+	)
+}
@@ -0,0 +1,196 @@
+package oncall
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// event is a VEVENT of an iCal file
+type event struct {
+	summary string
+	start   time.Time
+	end     time.Time
+	allDay  bool
+	// duration is the DURATION of events without DTEND
+	duration string
+}
+
+var icalEscapes = strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, "\n", `\N`, "\n", `\\`, `\`)
+
+var icalDurationPattern = regexp.MustCompile(
+	`^([+-])?P(?:(\d+)W|(\d+)D(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?|T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)$`)
+
+// parseICal reads the events of an iCal file. Times without a time zone are
+// in location. Recurrence rules aren't expanded, so every shift must be its
+// own event, as exported by most on-call tools
+func parseICal(r io.Reader, location *time.Location) ([]event, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []event
+	var current *event
+	// Components nested in the event, e.g. VALARM, whose properties are
+	// skipped
+	nested := 0
+
+	for _, line := range lines {
+		name, params, value := parseProperty(line)
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &event{}
+			nested = 0
+		case current == nil:
+			continue
+		case name == "BEGIN":
+			nested++
+		case name == "END" && value != "VEVENT":
+			nested--
+		case name == "END":
+			if current.start.IsZero() {
+				return nil, fmt.Errorf("oncall.parseICal: Event %q without DTSTART", current.summary)
+			}
+			if err := current.setDefaultEnd(); err != nil {
+				return nil, err
+			}
+			if current.end.After(current.start) {
+				events = append(events, *current)
+			}
+			current = nil
+		case nested > 0:
+			continue
+		case name == "SUMMARY":
+			current.summary = strings.TrimSpace(icalEscapes.Replace(value))
+		case name == "DURATION":
+			current.duration = value
+		case name == "DTSTART", name == "DTEND":
+			t, allDay, err := parseICalTime(value, params, location)
+			if err != nil {
+				return nil, fmt.Errorf("oncall.parseICal: Invalid %s \n%+v", name, err)
+			}
+			if name == "DTSTART" {
+				current.start = t
+				current.allDay = allDay
+			} else {
+				current.end = t
+			}
+		}
+	}
+
+	return events, nil
+}
+
+// setDefaultEnd sets the end of an event without DTEND from its DURATION.
+// Without either, all-day events last the day, and other events have no
+// duration, as in RFC 5545
+func (e *event) setDefaultEnd() error {
+	if !e.end.IsZero() {
+		return nil
+	}
+
+	switch {
+	case e.duration != "":
+		days, duration, err := parseICalDuration(e.duration)
+		if err != nil {
+			return fmt.Errorf("oncall.event.setDefaultEnd: Event %q: \n%+v", e.summary, err)
+		}
+		e.end = e.start.AddDate(0, 0, days).Add(duration)
+	case e.allDay:
+		e.end = e.start.AddDate(0, 0, 1)
+	default:
+		e.end = e.start
+	}
+
+	return nil
+}
+
+// parseICalDuration parses a DURATION value, e.g. "PT8H", "P1D" or "P1W".
+// Days and weeks are calendar days, so they're returned apart from the exact
+// duration, to keep the time of the day across DST changes
+func parseICalDuration(value string) (days int, duration time.Duration, err error) {
+	parts := icalDurationPattern.FindStringSubmatch(value)
+	if parts == nil {
+		return 0, 0, fmt.Errorf("oncall.parseICalDuration: Invalid duration %q", value)
+	}
+
+	number := func(i int) int {
+		n, _ := strconv.Atoi(parts[i])
+		return n
+	}
+
+	days = number(2)*7 + number(3)
+	duration = time.Duration(number(4)+number(7))*time.Hour +
+		time.Duration(number(5)+number(8))*time.Minute +
+		time.Duration(number(6)+number(9))*time.Second
+
+	if parts[1] == "-" {
+		return -days, -duration, nil
+	}
+	return days, duration, nil
+}
+
+// unfoldLines joins the lines continued on the next one, which starts with
+// a space or a tab
+func unfoldLines(r io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("oncall.unfoldLines: Error reading the iCal file \n%+v", err)
+	}
+	return lines, nil
+}
+
+// parseProperty splits e.g. "DTSTART;TZID=Europe/Berlin:20260105T090000"
+// into its name, parameters and value
+func parseProperty(line string) (name string, params map[string]string, value string) {
+	nameAndParams, value, _ := strings.Cut(line, ":")
+
+	parts := strings.Split(nameAndParams, ";")
+	params = map[string]string{}
+	for _, param := range parts[1:] {
+		key, paramValue, _ := strings.Cut(param, "=")
+		params[strings.ToUpper(key)] = strings.Trim(paramValue, `"`)
+	}
+
+	return strings.ToUpper(parts[0]), params, value
+}
+
+// parseICalTime parses a DATE or DATE-TIME value, in UTC when suffixed by Z,
+// else in its TZID or the default location
+func parseICalTime(value string, params map[string]string, location *time.Location) (t time.Time, allDay bool, err error) {
+	if tzid, ok := params["TZID"]; ok {
+		if location, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err = time.ParseInLocation("20060102", value, location)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+
+	t, err = time.ParseInLocation("20060102T150405", value, location)
+	return t, false, err
+}
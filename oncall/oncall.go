@@ -0,0 +1,255 @@
+package oncall
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+const defaultLength = 168 * time.Hour
+
+// Schedule is a parsed Rotation
+type Schedule struct {
+	name      string
+	users     []string
+	length    time.Duration
+	start     time.Time
+	location  *time.Location
+	overrides []shift
+	icalFile  string
+	icalUsers map[string]string
+}
+
+// shift is a user on call from start to end, end excluded
+type shift struct {
+	user  string
+	start time.Time
+	end   time.Time
+}
+
+// Schedules are the schedules by rotation name
+type Schedules map[string]*Schedule
+
+// Default are the Schedules used by the application, replaced on startup by
+// the configured ones
+var Default = Schedules{}
+
+// NewSchedulesFromConfig parses the rotations, and checks that the ones
+// referenced by the channels exist
+func NewSchedulesFromConfig(configs config.Config) (Schedules, error) {
+	schedules := Schedules{}
+
+	for name, rotation := range configs.Rotations {
+		schedule, err := Parse(name, rotation)
+		if err != nil {
+			return nil, fmt.Errorf("oncall.NewSchedulesFromConfig: \n%+v", err)
+		}
+		schedules[name] = schedule
+	}
+
+	for channelName, discordChannel := range configs.DiscordChannels {
+		if discordChannel.Rotation == "" {
+			continue
+		}
+		if _, ok := schedules[discordChannel.Rotation]; !ok {
+			return nil, fmt.Errorf("oncall.NewSchedulesFromConfig: Channel %s: Unknown rotation %q",
+				channelName, discordChannel.Rotation)
+		}
+	}
+
+	return schedules, nil
+}
+
+// OnCall returns the Discord user ID of the user on call in the rotation at
+// t, empty when nobody is
+func (s Schedules) OnCall(rotation string, t time.Time) (string, error) {
+	schedule, ok := s[rotation]
+	if !ok {
+		return "", fmt.Errorf("oncall.Schedules.OnCall: Unknown rotation %q", rotation)
+	}
+	return schedule.OnCall(t)
+}
+
+// Parse validates the rotation. The iCal file is read once, so an invalid
+// file is reported on startup
+func Parse(name string, rotation config.Rotation) (*Schedule, error) {
+	schedule := &Schedule{
+		name:      name,
+		users:     rotation.Users,
+		length:    defaultLength,
+		location:  time.UTC,
+		icalFile:  rotation.ICalFile,
+		icalUsers: rotation.ICalUsers,
+	}
+
+	if len(rotation.Users) == 0 && rotation.ICalFile == "" && len(rotation.Overrides) == 0 {
+		return nil, fmt.Errorf("oncall.Parse: Rotation %s: users, overrides or icalFile are needed", name)
+	}
+
+	if rotation.Timezone != "" {
+		location, err := time.LoadLocation(rotation.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("oncall.Parse: Rotation %s: Invalid timezone \n%+v", name, err)
+		}
+		schedule.location = location
+	}
+
+	if rotation.Length != "" {
+		length, err := time.ParseDuration(rotation.Length)
+		if err != nil || length <= 0 {
+			return nil, fmt.Errorf("oncall.Parse: Rotation %s: Invalid length %q", name, rotation.Length)
+		}
+		schedule.length = length
+	}
+
+	if len(rotation.Users) > 0 {
+		start, err := parseStart(rotation.Start, rotation.Handoff, schedule.location)
+		if err != nil {
+			return nil, fmt.Errorf("oncall.Parse: Rotation %s: \n%+v", name, err)
+		}
+		schedule.start = start
+	}
+
+	for i, override := range rotation.Overrides {
+		start, startErr := parseDate(override.Start, schedule.location)
+		end, endErr := parseDate(override.End, schedule.location)
+		if override.User == "" || startErr != nil || endErr != nil || !end.After(start) {
+			return nil, fmt.Errorf(
+				"oncall.Parse: Rotation %s: Invalid override %d, a user, a start and a later end are needed",
+				name, i)
+		}
+		schedule.overrides = append(schedule.overrides, shift{user: override.User, start: start, end: end})
+	}
+
+	if schedule.icalFile != "" {
+		if _, err := schedule.readICal(); err != nil {
+			return nil, fmt.Errorf("oncall.Parse: Rotation %s: \n%+v", name, err)
+		}
+	}
+
+	return schedule, nil
+}
+
+// OnCall returns the Discord user ID of the user on call at t, empty when
+// nobody is. Overrides come first, then the iCal events and the turns
+func (s *Schedule) OnCall(t time.Time) (string, error) {
+	for _, override := range s.overrides {
+		if override.covers(t) {
+			return override.user, nil
+		}
+	}
+
+	if s.icalFile != "" {
+		shifts, err := s.readICal()
+		if err != nil {
+			return "", err
+		}
+		for _, icalShift := range shifts {
+			if icalShift.covers(t) {
+				return icalShift.user, nil
+			}
+		}
+	}
+
+	if len(s.users) == 0 || t.Before(s.start) {
+		return "", nil
+	}
+
+	return s.users[s.turn(t)%len(s.users)], nil
+}
+
+// turn counts the turns started from the first handoff to t. Turns of whole
+// days are counted on the calendar of the time zone, so handoffs keep their
+// time of the day across DST changes
+func (s *Schedule) turn(t time.Time) int {
+	turn := int(t.Sub(s.start) / s.length)
+
+	if s.length%(24*time.Hour) != 0 {
+		return turn
+	}
+
+	days := int(s.length / (24 * time.Hour))
+	handoff := func(turn int) time.Time {
+		return s.start.AddDate(0, 0, turn*days)
+	}
+
+	for turn > 0 && handoff(turn).After(t) {
+		turn--
+	}
+	for !handoff(turn + 1).After(t) {
+		turn++
+	}
+
+	return turn
+}
+
+// readICal reads the shifts of the iCal file, mapping the event summaries to
+// Discord user IDs
+func (s *Schedule) readICal() ([]shift, error) {
+	file, err := os.Open(s.icalFile)
+	if err != nil {
+		return nil, fmt.Errorf("oncall.Schedule.readICal: Error reading the iCal file \n%+v", err)
+	}
+	defer file.Close()
+
+	events, err := parseICal(file, s.location)
+	if err != nil {
+		return nil, fmt.Errorf("oncall.Schedule.readICal: Error parsing %s \n%+v", s.icalFile, err)
+	}
+
+	shifts := make([]shift, 0, len(events))
+	for _, event := range events {
+		user, ok := s.icalUsers[event.summary]
+		if !ok {
+			if !isUserID(event.summary) {
+				return nil, fmt.Errorf(
+					"oncall.Schedule.readICal: Unknown user %q in %s, add it to icalUsers",
+					event.summary, s.icalFile)
+			}
+			user = event.summary
+		}
+		shifts = append(shifts, shift{user: user, start: event.start, end: event.end})
+	}
+
+	return shifts, nil
+}
+
+func (s shift) covers(t time.Time) bool {
+	return !t.Before(s.start) && t.Before(s.end)
+}
+
+// parseStart combines the start date and the handoff time of the day
+func parseStart(startDate, handoff string, location *time.Location) (time.Time, error) {
+	if startDate == "" {
+		return time.Time{}, fmt.Errorf("oncall.parseStart: The start date is needed with users")
+	}
+	if handoff == "" {
+		handoff = "00:00"
+	}
+
+	start, err := time.ParseInLocation("2006-01-02 15:04", startDate+" "+handoff, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("oncall.parseStart: Invalid start or handoff \n%+v", err)
+	}
+
+	return start, nil
+}
+
+// parseDate parses RFC 3339, or "2006-01-02 15:04" in the location
+func parseDate(value string, location *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.ParseInLocation("2006-01-02 15:04", value, location)
+}
+
+// isUserID tells whether value looks like a Discord user ID, a snowflake
+func isUserID(value string) bool {
+	_, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+	return err == nil
+}
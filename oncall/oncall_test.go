@@ -0,0 +1,200 @@
+package oncall
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("No time zone database: %v", err)
+	}
+	return location
+}
+
+func TestOnCall(t *testing.T) {
+	berlin := loadLocation(t, "Europe/Berlin")
+
+	weekly := config.Rotation{
+		Users:    []string{"1", "2", "3"},
+		Start:    "2026-03-02",
+		Handoff:  "09:00",
+		Timezone: "Europe/Berlin",
+	}
+	daily := config.Rotation{
+		Users:    []string{"1", "2"},
+		Length:   "24h",
+		Start:    "2026-03-27",
+		Handoff:  "09:00",
+		Timezone: "Europe/Berlin",
+	}
+	shifts := config.Rotation{
+		Users:  []string{"1", "2", "3"},
+		Length: "12h",
+		Start:  "2026-03-02",
+	}
+	overridden := weekly
+	overridden.Overrides = []config.RotationOverride{
+		{User: "9", Start: "2026-03-04 12:00", End: "2026-03-05 12:00"},
+	}
+	overridesOnly := config.Rotation{
+		Overrides: []config.RotationOverride{{User: "9", Start: "2026-03-04T12:00:00Z", End: "2026-03-05T12:00:00Z"}},
+	}
+
+	tests := []struct {
+		name     string
+		rotation config.Rotation
+		time     time.Time
+		want     string
+	}{
+		{"before the start", weekly, time.Date(2026, 3, 2, 8, 59, 0, 0, berlin), ""},
+		{"first turn", weekly, time.Date(2026, 3, 2, 9, 0, 0, 0, berlin), "1"},
+		{"end of the first turn", weekly, time.Date(2026, 3, 9, 8, 59, 0, 0, berlin), "1"},
+		{"second turn", weekly, time.Date(2026, 3, 9, 9, 0, 0, 0, berlin), "2"},
+		{"users wrap around", weekly, time.Date(2026, 3, 23, 9, 0, 0, 0, berlin), "1"},
+		{"handoff in local time after DST", weekly, time.Date(2026, 3, 30, 8, 59, 0, 0, berlin), "1"},
+		{"handoff after DST", weekly, time.Date(2026, 3, 30, 9, 0, 0, 0, berlin), "2"},
+		{"daily turn across DST", daily, time.Date(2026, 3, 29, 8, 59, 0, 0, berlin), "2"},
+		{"daily handoff after DST", daily, time.Date(2026, 3, 29, 9, 0, 0, 0, berlin), "1"},
+		{"turns shorter than a day", shifts, time.Date(2026, 3, 3, 11, 0, 0, 0, time.UTC), "3"},
+		{"next turn shorter than a day", shifts, time.Date(2026, 3, 3, 12, 0, 0, 0, time.UTC), "1"},
+		{"override", overridden, time.Date(2026, 3, 4, 12, 0, 0, 0, berlin), "9"},
+		{"override end excluded", overridden, time.Date(2026, 3, 5, 12, 0, 0, 0, berlin), "1"},
+		{"overrides only", overridesOnly, time.Date(2026, 3, 4, 13, 0, 0, 0, time.UTC), "9"},
+		{"nobody on call", overridesOnly, time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC), ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := Parse("platform", test.rotation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := schedule.OnCall(test.time)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("OnCall(%s) = %q, want %q", test.time, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseInvalidRotations(t *testing.T) {
+	tests := []struct {
+		name     string
+		rotation config.Rotation
+	}{
+		{"empty", config.Rotation{}},
+		{"users without start", config.Rotation{Users: []string{"1"}}},
+		{"invalid length", config.Rotation{Users: []string{"1"}, Start: "2026-03-02", Length: "-1h"}},
+		{"invalid handoff", config.Rotation{Users: []string{"1"}, Start: "2026-03-02", Handoff: "9am"}},
+		{"invalid timezone", config.Rotation{Users: []string{"1"}, Start: "2026-03-02", Timezone: "Mars/Olympus"}},
+		{"override without user", config.Rotation{Overrides: []config.RotationOverride{
+			{Start: "2026-03-04 12:00", End: "2026-03-05 12:00"}}}},
+		{"override ending before its start", config.Rotation{Overrides: []config.RotationOverride{
+			{User: "9", Start: "2026-03-05 12:00", End: "2026-03-04 12:00"}}}},
+		{"missing iCal file", config.Rotation{ICalFile: "testdata/missing.ics"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse("platform", test.rotation); err == nil {
+				t.Errorf("Parse() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestOnCallFromICal(t *testing.T) {
+	berlin := loadLocation(t, "Europe/Berlin")
+
+	rotation := config.Rotation{
+		Users:    []string{"7"},
+		Start:    "2026-01-01",
+		ICalFile: "testdata/google-calendar.ics",
+		ICalUsers: map[string]string{
+			"Alice":         "111111111111111111",
+			"Bob":           "222222222222222222",
+			"Carol, backup": "333333333333333333",
+		},
+		Overrides: []config.RotationOverride{
+			{User: "9", Start: "2026-03-10T00:00:00Z", End: "2026-03-11T00:00:00Z"},
+		},
+	}
+
+	schedule, err := Parse("platform", rotation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		time time.Time
+		want string
+	}{
+		{"event with DTEND and TZID", time.Date(2026, 3, 2, 9, 0, 0, 0, berlin), "111111111111111111"},
+		{"DTEND excluded", time.Date(2026, 3, 9, 8, 59, 0, 0, berlin), "111111111111111111"},
+		{"event with DURATION in days", time.Date(2026, 3, 9, 9, 0, 0, 0, berlin), "222222222222222222"},
+		{"DURATION of an alarm is ignored", time.Date(2026, 3, 12, 9, 0, 0, 0, berlin), "222222222222222222"},
+		{"DURATION ends the event", time.Date(2026, 3, 16, 9, 0, 0, 0, berlin), "123456789012345678"},
+		{"override before the iCal events", time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC), "9"},
+		{"all-day event without DTEND", time.Date(2026, 3, 16, 23, 59, 0, 0, berlin), "123456789012345678"},
+		{"event with DURATION in hours", time.Date(2026, 3, 17, 16, 29, 0, 0, time.UTC), "333333333333333333"},
+		{"after the DURATION, turns apply", time.Date(2026, 3, 17, 16, 30, 0, 0, time.UTC), "7"},
+		{"event without end has no duration", time.Date(2026, 3, 18, 12, 0, 0, 0, berlin), "7"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := schedule.OnCall(test.time)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("OnCall(%s) = %q, want %q", test.time, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseICalDuration(t *testing.T) {
+	tests := []struct {
+		value        string
+		wantDays     int
+		wantDuration time.Duration
+		wantErr      bool
+	}{
+		{"PT8H", 0, 8 * time.Hour, false},
+		{"PT8H30M", 0, 8*time.Hour + 30*time.Minute, false},
+		{"PT90S", 0, 90 * time.Second, false},
+		{"P1D", 1, 0, false},
+		{"P1DT12H", 1, 12 * time.Hour, false},
+		{"P2W", 14, 0, false},
+		{"+P1D", 1, 0, false},
+		{"-PT15M", 0, -15 * time.Minute, false},
+		{"-P0DT0H30M0S", 0, -30 * time.Minute, false},
+		{"P1W2D", 0, 0, true},
+		{"PT8", 0, 0, true},
+		{"8h", 0, 0, true},
+		{"", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		days, duration, err := parseICalDuration(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseICalDuration(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			continue
+		}
+		if days != test.wantDays || duration != test.wantDuration {
+			t.Errorf("parseICalDuration(%q) = %d days and %s, want %d days and %s",
+				test.value, days, duration, test.wantDays, test.wantDuration)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package redact
+
+import (
+	"io"
+	"regexp"
+)
+
+// Mask replaces secret values in logs and errors
+const Mask = "<redacted>"
+
+// webhookTokenRegexp matches the token of Discord webhook URLs, keeping the
+// webhook ID so the channel can still be identified. The host isn't matched,
+// so webhooks behind proxies are covered too
+var webhookTokenRegexp = regexp.MustCompile(
+	`(/api/(?:v\d+/)?webhooks/[0-9]+/)[A-Za-z0-9_\-.]+`)
+
+// String masks the webhook tokens found in s
+func String(s string) string {
+	return webhookTokenRegexp.ReplaceAllString(s, "${1}"+Mask)
+}
+
+// Error masks the webhook tokens found in the error message
+func Error(err error) string {
+	if err == nil {
+		return ""
+	}
+	return String(err.Error())
+}
+
+// writer masks webhook tokens in everything written to the underlying writer
+type writer struct {
+	w io.Writer
+}
+
+// NewWriter wraps w so that webhook tokens are masked before being written.
+// It's meant for line oriented outputs, such as loggers, which write each
+// entry in a single call
+func NewWriter(w io.Writer) io.Writer {
+	return writer{w: w}
+}
+
+func (r writer) Write(p []byte) (int, error) {
+	if _, err := r.w.Write(webhookTokenRegexp.ReplaceAll(p, []byte("${1}"+Mask))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,91 @@
+package redact
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			"webhook URL",
+			"https://discord.com/api/webhooks/123456/abcDEF-123_xyz.token",
+			"https://discord.com/api/webhooks/123456/<redacted>",
+		},
+		{
+			"versioned API",
+			"https://discord.com/api/v10/webhooks/123456/abcDEF?wait=true",
+			"https://discord.com/api/v10/webhooks/123456/<redacted>?wait=true",
+		},
+		{
+			"proxy",
+			"http://discord-proxy.internal:8080/api/webhooks/123456/abcDEF",
+			"http://discord-proxy.internal:8080/api/webhooks/123456/<redacted>",
+		},
+		{
+			"in an error",
+			`Post "https://discord.com/api/webhooks/1/tok": dial tcp: i/o timeout`,
+			`Post "https://discord.com/api/webhooks/1/<redacted>": dial tcp: i/o timeout`,
+		},
+		{
+			"several URLs",
+			"/api/webhooks/1/a and /api/webhooks/2/b",
+			"/api/webhooks/1/<redacted> and /api/webhooks/2/<redacted>",
+		},
+		{"webhook ID only", "https://discord.com/api/webhooks/123456", "https://discord.com/api/webhooks/123456"},
+		{"non-numeric ID", "https://example.com/api/webhooks/abc/def", "https://example.com/api/webhooks/abc/def"},
+		{"other text", "status code 400", "status code 400"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := String(test.value); got != test.want {
+				t.Errorf("String(%q) = %q, want %q", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	if got := Error(nil); got != "" {
+		t.Errorf("Error(nil) = %q, want empty", got)
+	}
+
+	err := errors.New("Post https://discord.com/api/webhooks/1/token failed")
+	if got, want := Error(err), "Post https://discord.com/api/webhooks/1/<redacted> failed"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("closed")
+}
+
+func TestWriter(t *testing.T) {
+	var output bytes.Buffer
+	writer := NewWriter(&output)
+
+	line := []byte(`level=ERROR msg="Post failed" url=https://discord.com/api/webhooks/1/token` + "\n")
+	n, err := writer.Write(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The length written is the one of the input, as io.Writer requires
+	if n != len(line) {
+		t.Errorf("Write() = %d, want %d", n, len(line))
+	}
+	if want := `level=ERROR msg="Post failed" url=https://discord.com/api/webhooks/1/<redacted>` + "\n"; output.String() != want {
+		t.Errorf("Wrote %q, want %q", output.String(), want)
+	}
+
+	if _, err := NewWriter(failingWriter{}).Write(line); err == nil {
+		t.Errorf("Expected the error of the underlying writer")
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kolesaev/alertmanager-discord/logging"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDHeader carries the request ID, taken from the incoming request
+// when set, e.g. by a proxy, and echoed in the response
+const requestIDHeader = "X-Request-ID"
+
+// requestLogger replaces gin's access log. Each request gets a logger with
+// its request ID, and its trace ID when the request is traced, carried by the
+// request's context down to the delivery to Discord. Health checks and
+// metrics scrapes are only logged in debug
+func requestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" {
+			requestID = logging.NewRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		requestLogger := logger.With("requestID", requestID)
+		if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.IsValid() {
+			requestLogger = requestLogger.With("traceID", spanContext.TraceID().String())
+		}
+		ctx := logging.WithRequestID(c.Request.Context(), requestID)
+		c.Request = c.Request.WithContext(logging.WithLogger(ctx, requestLogger))
+
+		startedAt := time.Now()
+		c.Next()
+
+		level := slog.LevelInfo
+		if c.Request.Method == http.MethodGet &&
+			(strings.HasPrefix(c.Request.URL.Path, "/-/") || c.Request.URL.Path == "/metrics") {
+			level = slog.LevelDebug
+		}
+
+		requestLogger.Log(c.Request.Context(), level, "Handled request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"duration", time.Since(startedAt),
+			"clientIP", c.ClientIP())
+	}
+}
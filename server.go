@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kolesaev/alertmanager-discord/health"
+)
+
+// rejectWhileDraining refuses new alerts with a 503 once the shutdown has
+// started, so Alertmanager retries them on another replica. Read-only
+// requests, such as the health endpoints, are still served
+func rejectWhileDraining(tracker *health.Tracker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && tracker.Draining() {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "shutting down"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// serve runs the server until SIGTERM or SIGINT, then drains it. Readiness
+// fails first, and alerts are still accepted for drainDelay, while the load
+// balancers stop sending traffic to this instance. Then new alerts are
+// refused and the requests in progress, whose deliveries to Discord are
+// synchronous, are given shutdownTimeout to complete. A second signal skips
+// the delay. stop is called once the server is drained, to end background
+// tasks
+func serve(s *http.Server, tracker *health.Tracker, drainDelay, shutdownTimeout string, stop func()) error {
+	delay, err := time.ParseDuration(drainDelay)
+	if err != nil {
+		return fmt.Errorf("main.serve: Invalid drainDelay \n%+v", err)
+	}
+
+	timeout, err := time.ParseDuration(shutdownTimeout)
+	if err != nil {
+		return fmt.Errorf("main.serve: Invalid shutdownTimeout \n%+v", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	defer signal.Stop(signals)
+
+	serverErrors := make(chan error, 1)
+	go func() {
+		serverErrors <- s.ListenAndServe()
+	}()
+
+	tracker.SetReady(true)
+
+	select {
+	case err := <-serverErrors:
+		return fmt.Errorf("main.serve: Error running the server \n%+v", err)
+	case received := <-signals:
+		slog.Info("Draining before exiting", "signal", received.String(), "delay", delay, "timeout", timeout)
+	}
+
+	tracker.SetReady(false)
+	defer stop()
+
+	select {
+	case <-time.After(delay):
+	case received := <-signals:
+		slog.Info("Skipping the drain delay", "signal", received.String())
+	}
+
+	tracker.StartDraining()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := s.Shutdown(ctx); err != nil {
+		return fmt.Errorf("main.serve: Deliveries in progress were cut off by the shutdown timeout \n%+v", err)
+	}
+
+	slog.Info("Server drained, exiting")
+	return nil
+}
@@ -0,0 +1,261 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+// Alert is the last known state of an alert in a channel
+type Alert struct {
+	Fingerprint  string            `json:"fingerprint"`
+	Channel      string            `json:"channel"`
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	// EndsAt is when Alertmanager considers the alert resolved if it's not
+	// updated before, zero when unknown
+	EndsAt    time.Time `json:"endsAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Tracker keeps the firing alerts of each channel, keyed by fingerprint, as
+// received in the webhooks. Resolved alerts are removed, and firing ones
+// expire once their EndsAt is past, or when they haven't been received for
+// expireAfter, in case Alertmanager stops sending them
+type Tracker struct {
+	mutex       sync.RWMutex
+	channels    map[string]map[string]*Alert
+	expireAfter time.Duration
+	path        string
+	now         func() time.Time
+}
+
+// Default is the Tracker used by the application, replaced on startup by the
+// configured one
+var Default = NewTracker(0, "")
+
+// NewTracker creates an empty Tracker. When path is set, the state is saved
+// there on each update, see Load
+func NewTracker(expireAfter time.Duration, path string) *Tracker {
+	return &Tracker{
+		channels:    map[string]map[string]*Alert{},
+		expireAfter: expireAfter,
+		path:        path,
+		now:         time.Now,
+	}
+}
+
+// NewTrackerFromConfig creates the Tracker defined by the AlertState config,
+// loading the persisted state if any
+func NewTrackerFromConfig(stateConfig config.AlertStateConfig) (*Tracker, error) {
+	var expireAfter time.Duration
+	if stateConfig.ExpireAfter != "" {
+		var err error
+		expireAfter, err = time.ParseDuration(stateConfig.ExpireAfter)
+		if err != nil {
+			return nil, fmt.Errorf("state.NewTrackerFromConfig: Invalid expireAfter \n%+v", err)
+		}
+	}
+
+	tracker := NewTracker(expireAfter, stateConfig.Path)
+	if err := tracker.Load(); err != nil {
+		return nil, err
+	}
+
+	return tracker, nil
+}
+
+// Update records the alerts of a webhook received for the channel
+func (t *Tracker) Update(channel string, alerts []alertmanager.Alert) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+
+	channelAlerts, ok := t.channels[channel]
+	if !ok {
+		channelAlerts = map[string]*Alert{}
+		t.channels[channel] = channelAlerts
+	}
+
+	for _, alert := range alerts {
+		fingerprint := getFingerprint(alert)
+
+		if alert.Status != "firing" {
+			delete(channelAlerts, fingerprint)
+			continue
+		}
+
+		channelAlerts[fingerprint] = &Alert{
+			Fingerprint:  fingerprint,
+			Channel:      channel,
+			Status:       alert.Status,
+			Labels:       alert.Labels,
+			Annotations:  alert.Annotations,
+			GeneratorURL: alert.GeneratorURL,
+			StartsAt:     parseTime(alert.StartsAt),
+			EndsAt:       parseTime(alert.EndsAt),
+			UpdatedAt:    now,
+		}
+	}
+
+	t.expire(now)
+
+	return t.save()
+}
+
+// Active returns the firing alerts of the channel, or of every channel when
+// it's empty, ordered by channel, start time and fingerprint
+func (t *Tracker) Active(channel string) []Alert {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	now := t.now()
+
+	alerts := []Alert{}
+	for channelName, channelAlerts := range t.channels {
+		if channel != "" && channelName != channel {
+			continue
+		}
+		for _, alert := range channelAlerts {
+			if !t.expired(*alert, now) {
+				alerts = append(alerts, *alert)
+			}
+		}
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		a, b := alerts[i], alerts[j]
+		if a.Channel != b.Channel {
+			return a.Channel < b.Channel
+		}
+		if !a.StartsAt.Equal(b.StartsAt) {
+			return a.StartsAt.Before(b.StartsAt)
+		}
+		return a.Fingerprint < b.Fingerprint
+	})
+
+	return alerts
+}
+
+// Load reads the state saved at the Tracker's path, if any
+func (t *Tracker) Load() error {
+	if t.path == "" {
+		return nil
+	}
+
+	contents, err := os.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("state.Load: Error reading %s \n%+v", t.path, err)
+	}
+
+	var alerts []Alert
+	if err := json.Unmarshal(contents, &alerts); err != nil {
+		return fmt.Errorf("state.Load: Error parsing %s \n%+v", t.path, err)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for i := range alerts {
+		alert := alerts[i]
+		if _, ok := t.channels[alert.Channel]; !ok {
+			t.channels[alert.Channel] = map[string]*Alert{}
+		}
+		t.channels[alert.Channel][alert.Fingerprint] = &alert
+	}
+	t.expire(t.now())
+
+	return nil
+}
+
+func (t *Tracker) expired(alert Alert, now time.Time) bool {
+	if !alert.EndsAt.IsZero() && alert.EndsAt.Before(now) {
+		return true
+	}
+	return t.expireAfter > 0 && now.Sub(alert.UpdatedAt) > t.expireAfter
+}
+
+func (t *Tracker) expire(now time.Time) {
+	for _, channelAlerts := range t.channels {
+		for fingerprint, alert := range channelAlerts {
+			if t.expired(*alert, now) {
+				delete(channelAlerts, fingerprint)
+			}
+		}
+	}
+}
+
+// save writes the state to the Tracker's path, through a temporary file so a
+// crash doesn't leave it half written
+func (t *Tracker) save() error {
+	if t.path == "" {
+		return nil
+	}
+
+	alerts := []*Alert{}
+	for _, channelAlerts := range t.channels {
+		for _, alert := range channelAlerts {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	contents, err := json.Marshal(alerts)
+	if err != nil {
+		return fmt.Errorf("state.save: Error marshaling the state \n%+v", err)
+	}
+
+	temporary := t.path + ".tmp"
+	if err := os.WriteFile(temporary, contents, 0o600); err != nil {
+		return fmt.Errorf("state.save: Error writing %s \n%+v", temporary, err)
+	}
+	if err := os.Rename(temporary, filepath.Clean(t.path)); err != nil {
+		return fmt.Errorf("state.save: Error replacing %s \n%+v", t.path, err)
+	}
+
+	return nil
+}
+
+// getFingerprint returns the alert's fingerprint, or one made of its sorted
+// labels when Alertmanager didn't send it
+func getFingerprint(alert alertmanager.Alert) string {
+	if alert.Fingerprint != "" {
+		return alert.Fingerprint
+	}
+
+	names := make([]string, 0, len(alert.Labels))
+	for name := range alert.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%q", name, alert.Labels[name])
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// parseTime parses Alertmanager's times, which are zero ("0001-01-01...")
+// when unset
+func parseTime(value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil || parsed.Year() <= 1 {
+		return time.Time{}
+	}
+	return parsed
+}
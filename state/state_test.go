@@ -0,0 +1,168 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+func fingerprints(alerts []Alert) []string {
+	names := make([]string, len(alerts))
+	for i, alert := range alerts {
+		names[i] = alert.Fingerprint
+	}
+	return names
+}
+
+func TestUpdate(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tracker := NewTracker(0, "")
+	tracker.now = func() time.Time { return now }
+
+	err := tracker.Update("prod", []alertmanager.Alert{
+		{Status: "firing", Fingerprint: "b", StartsAt: "2026-03-10T10:00:00Z"},
+		{Status: "firing", Fingerprint: "a", StartsAt: "2026-03-10T11:00:00Z", EndsAt: "0001-01-01T00:00:00Z"},
+		{Status: "resolved", Fingerprint: "c", StartsAt: "2026-03-10T09:00:00Z"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracker.Update("staging", []alertmanager.Alert{
+		{Status: "firing", Fingerprint: "d", StartsAt: "2026-03-10T08:00:00Z"},
+	})
+
+	if got := fingerprints(tracker.Active("")); !reflect.DeepEqual(got, []string{"b", "a", "d"}) {
+		t.Errorf("Active() = %v, want the firing alerts by channel and start", got)
+	}
+
+	active := tracker.Active("prod")
+	if got := fingerprints(active); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Fatalf("Active(prod) = %v, want b and a", got)
+	}
+	if !active[1].EndsAt.IsZero() || !active[1].UpdatedAt.Equal(now) || active[1].Channel != "prod" {
+		t.Errorf("Unexpected alert %+v", active[1])
+	}
+
+	// Resolved alerts are removed
+	tracker.Update("prod", []alertmanager.Alert{{Status: "resolved", Fingerprint: "b"}})
+	if got := fingerprints(tracker.Active("prod")); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("Active(prod) = %v, want a", got)
+	}
+}
+
+func TestFingerprintFromLabels(t *testing.T) {
+	tracker := NewTracker(0, "")
+	labels := map[string]string{"alertname": "HighLatency", "job": "api"}
+
+	tracker.Update("prod", []alertmanager.Alert{{Status: "firing", Labels: labels}})
+	active := tracker.Active("prod")
+	if len(active) != 1 || active[0].Fingerprint != `{alertname="HighLatency",job="api"}` {
+		t.Fatalf("Active() = %+v, want a fingerprint made of the labels", active)
+	}
+
+	tracker.Update("prod", []alertmanager.Alert{{Status: "resolved", Labels: labels}})
+	if active := tracker.Active("prod"); len(active) != 0 {
+		t.Errorf("Active() = %+v, want the alert resolved by its labels", active)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tracker := NewTracker(time.Hour, "")
+	tracker.now = func() time.Time { return now }
+
+	tracker.Update("prod", []alertmanager.Alert{
+		{Status: "firing", Fingerprint: "ends", EndsAt: "2026-03-10T12:30:00Z"},
+		{Status: "firing", Fingerprint: "stale"},
+	})
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  []string
+	}{
+		{"both active", 0, []string{"ends", "stale"}},
+		{"past endsAt", 45 * time.Minute, []string{"stale"}},
+		{"not received for expireAfter", 2 * time.Hour, []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker.now = func() time.Time { return now.Add(test.after) }
+			if got := fingerprints(tracker.Active("prod")); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Active() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	tracker, err := NewTrackerFromConfig(config.AlertStateConfig{ExpireAfter: "24h", Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tracker.Update("prod", []alertmanager.Alert{
+		{Status: "firing", Fingerprint: "a", Labels: map[string]string{"alertname": "HighLatency"}},
+		{Status: "firing", Fingerprint: "expired", EndsAt: "2020-01-01T00:00:00Z"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewTrackerFromConfig(config.AlertStateConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := loaded.Active("")
+	if len(active) != 1 || active[0].Fingerprint != "a" || active[0].Labels["alertname"] != "HighLatency" {
+		t.Errorf("Loaded %+v, want the saved alert", active)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected the temporary file to be renamed")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte("{not json"), 0o600)
+
+	tests := []struct {
+		name        string
+		stateConfig config.AlertStateConfig
+		wantErr     bool
+	}{
+		{"no path", config.AlertStateConfig{}, false},
+		{"missing file", config.AlertStateConfig{Path: filepath.Join(dir, "missing.json")}, false},
+		{"invalid file", config.AlertStateConfig{Path: invalid}, true},
+		{"unreadable file", config.AlertStateConfig{Path: dir}, true},
+		{"invalid expireAfter", config.AlertStateConfig{ExpireAfter: "a day"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewTrackerFromConfig(test.stateConfig)
+			if (err != nil) != test.wantErr {
+				t.Errorf("NewTrackerFromConfig() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestSaveError(t *testing.T) {
+	tracker := NewTracker(0, filepath.Join(t.TempDir(), "missing", "state.json"))
+	if err := tracker.Update("prod", []alertmanager.Alert{{Status: "firing", Fingerprint: "a"}}); err == nil {
+		t.Errorf("Expected an error saving to a missing directory")
+	}
+	// The state is still updated in memory
+	if active := tracker.Active("prod"); len(active) != 1 {
+		t.Errorf("Active() = %+v, want the alert", active)
+	}
+}
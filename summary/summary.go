@@ -0,0 +1,156 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/discord"
+	"github.com/kolesaev/alertmanager-discord/history"
+	"github.com/kolesaev/alertmanager-discord/state"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	defaultPeriod        = 24 * time.Hour
+	defaultLongestFiring = 5
+)
+
+// Start schedules the summary of every channel with a schedule, until ctx is
+// done. Firing alerts are taken from the alert state, and the alerts fired
+// and resolved over the period from the history, so they aren't counted when
+// it's disabled
+func Start(ctx context.Context, configs config.Config, alertState *state.Tracker, store history.Store) error {
+	scheduler := cron.New()
+
+	channelNames := make([]string, 0, len(configs.DiscordChannels))
+	for channelName := range configs.DiscordChannels {
+		channelNames = append(channelNames, channelName)
+	}
+	sort.Strings(channelNames)
+
+	for _, channelName := range channelNames {
+		summaryConfig := configs.DiscordChannels[channelName].Summary
+		if summaryConfig.Schedule == "" {
+			continue
+		}
+
+		schedule, err := parseSchedule(summaryConfig)
+		if err != nil {
+			return fmt.Errorf("summary.Start: Channel %s: \n%+v", channelName, err)
+		}
+
+		period := defaultPeriod
+		if summaryConfig.Period != "" {
+			if period, err = time.ParseDuration(summaryConfig.Period); err != nil {
+				return fmt.Errorf("summary.Start: Channel %s: Invalid period \n%+v", channelName, err)
+			}
+		}
+
+		channelName := channelName
+		scheduler.Schedule(schedule, cron.FuncJob(func() {
+			report := BuildReport(channelName, summaryConfig, period, alertState, store, time.Now())
+			if !summaryConfig.SendWhenEmpty &&
+				len(report.Firing) == 0 && report.FiredCount == 0 && report.ResolvedCount == 0 {
+				return
+			}
+
+			if _, err := discord.SendSummary(ctx, report, configs); err != nil {
+				slog.Error("Error sending summary", "channel", channelName, "error", err)
+			}
+		}))
+
+		slog.Info("Scheduled summary", "channel", channelName,
+			"schedule", summaryConfig.Schedule, "timezone", summaryConfig.Timezone)
+		if store == history.Discard {
+			slog.Warn("The summary won't count the fired and resolved alerts, set history.enabled to count them",
+				"channel", channelName)
+		}
+	}
+
+	scheduler.Start()
+	go func() {
+		<-ctx.Done()
+		scheduler.Stop()
+	}()
+
+	return nil
+}
+
+// BuildReport gathers the channel's summary at now
+func BuildReport(
+	channelName string,
+	summaryConfig config.SummaryConfig,
+	period time.Duration,
+	alertState *state.Tracker,
+	store history.Store,
+	now time.Time) discord.SummaryReport {
+
+	report := discord.SummaryReport{
+		Channel:       channelName,
+		Time:          now,
+		Firing:        alertState.Active(channelName),
+		Period:        period,
+		LongestFiring: summaryConfig.LongestFiring,
+	}
+	if report.LongestFiring <= 0 {
+		report.LongestFiring = defaultLongestFiring
+	}
+
+	if store == history.Discard {
+		report.CountsUnavailable = true
+		return report
+	}
+
+	entries, err := store.Query(history.Filter{Channel: channelName, Since: now.Add(-period)})
+	if err != nil {
+		slog.Error("Error reading the history for the summary", "channel", channelName, "error", err)
+		report.CountsUnavailable = true
+		return report
+	}
+
+	fired, resolved := map[string]bool{}, map[string]bool{}
+	for _, entry := range entries {
+		for _, alert := range entry.Alerts {
+			key := alert.Fingerprint
+			if key == "" {
+				key = alert.AlertName
+			}
+
+			switch alert.Status {
+			case "firing":
+				fired[key] = true
+			case "resolved":
+				resolved[key] = true
+			}
+		}
+	}
+	report.FiredCount, report.ResolvedCount = len(fired), len(resolved)
+
+	return report
+}
+
+// parseSchedule parses the cron expression in the summary's time zone
+func parseSchedule(summaryConfig config.SummaryConfig) (cron.Schedule, error) {
+	location := time.UTC
+	if summaryConfig.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(summaryConfig.Timezone); err != nil {
+			return nil, fmt.Errorf("summary.parseSchedule: Invalid timezone \n%+v", err)
+		}
+	}
+
+	schedule, err := cron.ParseStandard(summaryConfig.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("summary.parseSchedule: Invalid schedule %q \n%+v", summaryConfig.Schedule, err)
+	}
+
+	if specSchedule, ok := schedule.(*cron.SpecSchedule); ok {
+		specSchedule.Location = location
+	}
+
+	return schedule, nil
+}
@@ -0,0 +1,137 @@
+package summary
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/history"
+	"github.com/kolesaev/alertmanager-discord/state"
+)
+
+type failingStore struct{}
+
+func (failingStore) Add(history.Entry) error { return nil }
+
+func (failingStore) Query(history.Filter) ([]history.Entry, error) {
+	return nil, errors.New("disk failure")
+}
+
+func TestBuildReport(t *testing.T) {
+	now := time.Now()
+
+	alertState := state.NewTracker(0, "")
+	err := alertState.Update("prod", []alertmanager.Alert{
+		{Status: "firing", Labels: map[string]string{"alertname": "HighLatency"}, StartsAt: "2024-01-01T00:00:00Z"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = alertState.Update("staging", []alertmanager.Alert{
+		{Status: "firing", Labels: map[string]string{"alertname": "DiskFilling"}, StartsAt: "2024-01-01T00:00:00Z"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := history.NewMemoryStore(10, 0)
+	entries := []history.Entry{
+		{Time: now.Add(-48 * time.Hour), Channel: "prod", Alerts: []history.AlertSummary{
+			{Fingerprint: "old", Status: "firing"},
+		}},
+		{Time: now.Add(-2 * time.Hour), Channel: "prod", Alerts: []history.AlertSummary{
+			{Fingerprint: "a", Status: "firing"},
+			{Fingerprint: "b", Status: "firing"},
+		}},
+		// Repeated notifications of the same alerts are counted once
+		{Time: now.Add(-time.Hour), Channel: "prod", Alerts: []history.AlertSummary{
+			{Fingerprint: "a", Status: "firing"},
+			{Fingerprint: "b", Status: "resolved"},
+			{AlertName: "NoFingerprint", Status: "resolved"},
+		}},
+		{Time: now.Add(-time.Hour), Channel: "staging", Alerts: []history.AlertSummary{
+			{Fingerprint: "c", Status: "firing"},
+		}},
+	}
+	for _, entry := range entries {
+		store.Add(entry)
+	}
+
+	tests := []struct {
+		name              string
+		store             history.Store
+		summaryConfig     config.SummaryConfig
+		wantFired         int
+		wantResolved      int
+		wantUnavailable   bool
+		wantLongestFiring int
+	}{
+		{"counts over the period", store, config.SummaryConfig{}, 2, 2, false, 5},
+		{"longest firing", store, config.SummaryConfig{LongestFiring: 3}, 2, 2, false, 3},
+		{"history disabled", history.Discard, config.SummaryConfig{}, 0, 0, true, 5},
+		{"history failing", failingStore{}, config.SummaryConfig{}, 0, 0, true, 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := BuildReport("prod", test.summaryConfig, 24*time.Hour, alertState, test.store, now)
+
+			if len(report.Firing) != 1 || report.Firing[0].Labels["alertname"] != "HighLatency" {
+				t.Errorf("Firing = %+v, want the HighLatency alert of the channel", report.Firing)
+			}
+			if report.FiredCount != test.wantFired || report.ResolvedCount != test.wantResolved {
+				t.Errorf("%d fired and %d resolved, want %d and %d",
+					report.FiredCount, report.ResolvedCount, test.wantFired, test.wantResolved)
+			}
+			if report.CountsUnavailable != test.wantUnavailable {
+				t.Errorf("CountsUnavailable = %v, want %v", report.CountsUnavailable, test.wantUnavailable)
+			}
+			if report.LongestFiring != test.wantLongestFiring {
+				t.Errorf("LongestFiring = %d, want %d", report.LongestFiring, test.wantLongestFiring)
+			}
+			if report.Channel != "prod" || report.Period != 24*time.Hour || !report.Time.Equal(now) {
+				t.Errorf("Unexpected report %+v", report)
+			}
+		})
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("No time zone database")
+	}
+
+	tests := []struct {
+		name          string
+		summaryConfig config.SummaryConfig
+		after         time.Time
+		want          time.Time
+		wantErr       bool
+	}{
+		{"UTC by default", config.SummaryConfig{Schedule: "0 9 * * *"},
+			time.Date(2026, 3, 10, 10, 0, 0, 0, time.UTC), time.Date(2026, 3, 11, 9, 0, 0, 0, time.UTC), false},
+		{"time zone", config.SummaryConfig{Schedule: "0 9 * * 1-5", Timezone: "Europe/Berlin"},
+			time.Date(2026, 3, 13, 9, 0, 0, 0, berlin), time.Date(2026, 3, 16, 9, 0, 0, 0, berlin), false},
+		{"invalid schedule", config.SummaryConfig{Schedule: "every day"}, time.Time{}, time.Time{}, true},
+		{"invalid time zone", config.SummaryConfig{Schedule: "0 9 * * *", Timezone: "Mars/Olympus"},
+			time.Time{}, time.Time{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := parseSchedule(test.summaryConfig)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseSchedule() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+			if next := schedule.Next(test.after); !next.Equal(test.want) {
+				t.Errorf("Next(%s) = %s, want %s", test.after, next, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/discord"
+	"github.com/kolesaev/alertmanager-discord/filter"
+	"github.com/kolesaev/alertmanager-discord/oncall"
+	"github.com/kolesaev/alertmanager-discord/redact"
+	"github.com/kolesaev/alertmanager-discord/windows"
+)
+
+// runTestChannel implements the test-channel subcommand, which runs a
+// synthetic alert through the normal pipeline and prints Discord's response.
+// The config is loaded as for the server, from --config, the other config
+// flags, CONFIG_PATH and AMD_* variables
+func runTestChannel(args []string) int {
+	channelName, options, configArgs, err := parseTestChannelArgs(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+
+	configs := config.LoadUserConfig(configArgs)
+
+	registry, err := windows.NewRegistryFromConfig(*configs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	windows.Default = registry
+
+	schedules, err := oncall.NewSchedulesFromConfig(*configs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	oncall.Default = schedules
+
+	filterSets, err := filter.NewSetsFromConfig(*configs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	filter.Default = filterSets
+
+	body, err := alertmanager.NewSyntheticMessageBody(options, configs.Severity.Label, channelName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	result, err := discord.SendAlerts(context.Background(), channelName, body, *configs)
+
+	output := redact.NewWriter(os.Stdout)
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(result)
+
+	if err != nil {
+		fmt.Fprintln(redact.NewWriter(os.Stderr), err)
+		return 1
+	}
+	return 0
+}
+
+// parseTestChannelArgs parses the test-channel arguments, returning the
+// channel, the test alerts options and the config flags to load the config
+// with. Flags can be given before or after the channel. Errors and usage are
+// written to output
+func parseTestChannelArgs(args []string, output io.Writer) (string, alertmanager.SyntheticOptions, []string, error) {
+	flagSet := flag.NewFlagSet("test-channel", flag.ContinueOnError)
+	flagSet.SetOutput(output)
+	flagSet.Usage = func() {
+		fmt.Fprintln(flagSet.Output(), "Usage: alertmanager-discord test-channel [flags] <channel> [flags]")
+		flagSet.PrintDefaults()
+	}
+
+	var options alertmanager.SyntheticOptions
+	flagSet.StringVar(&options.AlertName, "alertname", "TestAlert", "Name of the test alerts")
+	flagSet.StringVar(&options.Severity, "severity", "", "Severity of the test alerts")
+	flagSet.StringVar(&options.Status, "status", "firing", "Status of the test alerts, firing or resolved")
+	flagSet.IntVar(&options.Count, "count", 1, "Number of test alerts")
+	configArgs := config.ForwardFlags(flagSet)
+
+	// The flag package stops at the first positional argument, so the
+	// arguments after it are parsed again, until "--" ends the flags
+	positional := []string{}
+	for {
+		if err := flagSet.Parse(args); err != nil {
+			return "", options, nil, err
+		}
+
+		rest := flagSet.Args()
+		if len(rest) == 0 {
+			break
+		}
+		if parsed := len(args) - len(rest); parsed > 0 && args[parsed-1] == "--" {
+			positional = append(positional, rest...)
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	if len(positional) != 1 {
+		flagSet.Usage()
+		return "", options, nil, fmt.Errorf("main.parseTestChannelArgs: Expected one channel, got %d", len(positional))
+	}
+
+	return positional[0], options, configArgs(), nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+)
+
+func TestParseTestChannelArgs(t *testing.T) {
+	tests := []struct {
+		name           string
+		args           []string
+		wantChannel    string
+		wantOptions    alertmanager.SyntheticOptions
+		wantConfigArgs []string
+		wantErr        bool
+	}{
+		{
+			name:           "channel only",
+			args:           []string{"prod"},
+			wantChannel:    "prod",
+			wantOptions:    alertmanager.SyntheticOptions{AlertName: "TestAlert", Status: "firing", Count: 1},
+			wantConfigArgs: []string{},
+		},
+		{
+			name:           "flags before the channel",
+			args:           []string{"--config", "config.yaml", "--severity", "critical", "--count", "3", "prod"},
+			wantChannel:    "prod",
+			wantOptions:    alertmanager.SyntheticOptions{AlertName: "TestAlert", Severity: "critical", Status: "firing", Count: 3},
+			wantConfigArgs: []string{"--config=config.yaml"},
+		},
+		{
+			name:           "flags after the channel",
+			args:           []string{"prod", "--severity", "critical", "--status=resolved", "--username", "Alertmanager"},
+			wantChannel:    "prod",
+			wantOptions:    alertmanager.SyntheticOptions{AlertName: "TestAlert", Severity: "critical", Status: "resolved", Count: 1},
+			wantConfigArgs: []string{"--username=Alertmanager"},
+		},
+		{
+			name:           "channel after --",
+			args:           []string{"--alertname", "HighLatency", "--", "--prod"},
+			wantChannel:    "--prod",
+			wantOptions:    alertmanager.SyntheticOptions{AlertName: "HighLatency", Status: "firing", Count: 1},
+			wantConfigArgs: []string{},
+		},
+		{name: "no channel", args: []string{"--severity", "critical"}, wantErr: true},
+		{name: "two channels", args: []string{"prod", "staging"}, wantErr: true},
+		{name: "unknown flag", args: []string{"prod", "--sevrity", "critical"}, wantErr: true},
+		{name: "invalid count", args: []string{"prod", "--count", "many"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			channel, options, configArgs, err := parseTestChannelArgs(test.args, io.Discard)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseTestChannelArgs() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if test.wantErr {
+				return
+			}
+
+			if channel != test.wantChannel {
+				t.Errorf("channel = %q, want %q", channel, test.wantChannel)
+			}
+			if options != test.wantOptions {
+				t.Errorf("options = %+v, want %+v", options, test.wantOptions)
+			}
+			if !reflect.DeepEqual(configArgs, test.wantConfigArgs) {
+				t.Errorf("configArgs = %v, want %v", configArgs, test.wantConfigArgs)
+			}
+		})
+	}
+}
+
+func TestParseTestChannelArgsHelp(t *testing.T) {
+	if _, _, _, err := parseTestChannelArgs([]string{"prod", "--help"}, io.Discard); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("parseTestChannelArgs() error = %v, want %v", err, flag.ErrHelp)
+	}
+}
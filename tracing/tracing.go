@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/kolesaev/alertmanager-discord"
+
+// MemoryExporter holds the spans when the "memory" exporter is configured,
+// so they can be inspected in tests
+var MemoryExporter = tracetest.NewInMemoryExporter()
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. When tracing is disabled, spans are no-ops. The returned
+// function flushes the pending spans and must be called before exiting
+func Setup(ctx context.Context, tracingConfig config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !tracingConfig.Enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := newExporter(ctx, tracingConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	processor := sdktrace.NewBatchSpanProcessor(exporter)
+	if tracingConfig.Exporter == "memory" {
+		// Spans can be read as soon as they end
+		processor = sdktrace.NewSimpleSpanProcessor(exporter)
+	}
+
+	provider := NewTracerProvider(processor, tracingConfig)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewTracerProvider creates a tracer provider sending the ended spans to
+// processor
+func NewTracerProvider(processor sdktrace.SpanProcessor, tracingConfig config.TracingConfig) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(tracingConfig.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", tracingConfig.ServiceName))),
+	)
+}
+
+func newExporter(ctx context.Context, tracingConfig config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch tracingConfig.Exporter {
+	case "", "otlp":
+		options := []otlptracehttp.Option{}
+		if tracingConfig.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpoint(tracingConfig.Endpoint))
+		}
+		if tracingConfig.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+
+		exporter, err := otlptracehttp.New(ctx, options...)
+		if err != nil {
+			return nil, fmt.Errorf("tracing.newExporter: Error creating the OTLP exporter \n%+v", err)
+		}
+		return exporter, nil
+	case "memory":
+		return MemoryExporter, nil
+	}
+
+	return nil, fmt.Errorf("tracing.newExporter: Invalid exporter %q, should be otlp or memory", tracingConfig.Exporter)
+}
+
+// Start starts a span as a child of the one in ctx, using the global tracer
+// provider
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, options...)
+}
+
+// End records err, if any, on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kolesaev/alertmanager-discord/alertmanager"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/discord"
+	"github.com/kolesaev/alertmanager-discord/logging"
+	"github.com/kolesaev/alertmanager-discord/state"
+)
+
+// registerWebhookRoute adds the endpoint receiving Alertmanager's webhooks,
+// one per channel, and sending their alerts to Discord
+func registerWebhookRoute(router *gin.Engine, tracker *state.Tracker, configs config.Config) {
+	router.POST("/:channel", func(c *gin.Context) {
+		channelName := c.Param("channel")
+
+		var alertmanagerBody alertmanager.MessageBody
+		if err := c.ShouldBindJSON(&alertmanagerBody); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx := c.Request.Context()
+
+		// Every webhook updates the alert state, even if no message is sent
+		if _, ok := configs.DiscordChannels[channelName]; ok {
+			if err := tracker.Update(channelName, alertmanagerBody.Alerts); err != nil {
+				logging.FromContext(ctx).Error("Error updating the alert state", "channel", channelName, "error", err)
+			}
+		}
+
+		if _, err := discord.SendAlerts(ctx, channelName, alertmanagerBody, configs); err != nil {
+			logging.FromContext(ctx).Error("Error sending alerts", "channel", channelName, "error", err)
+		}
+
+		c.String(http.StatusOK, "Channel: %s", channelName)
+	})
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kolesaev/alertmanager-discord/config"
+	"github.com/kolesaev/alertmanager-discord/state"
+	"github.com/kolesaev/alertmanager-discord/tracing"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// loadTestConfig loads a config file with the given contents, on top of the
+// defaults
+func loadTestConfig(t *testing.T, contents string) config.Config {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return *config.LoadUserConfig([]string{"--config", path})
+}
+
+func TestWebhookSpans(t *testing.T) {
+	shutdown, err := tracing.Setup(context.Background(), config.TracingConfig{
+		Enabled:     true,
+		Exporter:    "memory",
+		ServiceName: "alertmanager-discord",
+		SampleRatio: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+	tracing.MemoryExporter.Reset()
+
+	traceparents := map[string]string{}
+	grafanaServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents["grafana"] = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\n"))
+	}))
+	defer grafanaServer.Close()
+
+	discordServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents["discord"] = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1"}`))
+	}))
+	defer discordServer.Close()
+
+	configs := loadTestConfig(t, `
+grafanaImage:
+  enabled: true
+  url: `+grafanaServer.URL+`
+channels:
+  default:
+    name: default
+    webhookURL: `+discordServer.URL+`/api/webhooks/1/token
+`)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(requestTracer())
+	registerWebhookRoute(router, state.NewTracker(0, ""), configs)
+
+	const parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	request := httptest.NewRequest(http.MethodPost, "/default", strings.NewReader(`{
+		"status": "firing",
+		"alerts": [{
+			"status": "firing",
+			"labels": {"alertname": "HighLatency", "severity": "critical"},
+			"annotations": {"__dashboardUid__": "abc", "__panelId__": "2"},
+			"startsAt": "2024-01-01T00:00:00Z"
+		}]
+	}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("traceparent", "00-"+parentTraceID+"-00f067aa0ba902b7-01")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range tracing.MemoryExporter.GetSpans() {
+		spans[span.Name] = span
+	}
+
+	// Each span and the name of its parent, the server span continuing the
+	// caller's trace
+	tree := []struct {
+		name   string
+		parent string
+	}{
+		{"POST /:channel", ""},
+		{"discord.SendAlerts", "POST /:channel"},
+		{"alertmanager.ExtractBodyInfo", "discord.SendAlerts"},
+		{"discord.createDiscordMessage", "discord.SendAlerts"},
+		{"grafana.RenderPanel", "discord.createDiscordMessage"},
+		{"discord.postMessage", "discord.SendAlerts"},
+	}
+
+	for _, node := range tree {
+		span, ok := spans[node.name]
+		if !ok {
+			t.Fatalf("Missing span %s, got %v", node.name, spanNames(spans))
+		}
+		if span.SpanContext.TraceID().String() != parentTraceID {
+			t.Errorf("Span %s isn't in the caller's trace", node.name)
+		}
+
+		if node.parent == "" {
+			if !span.Parent.IsRemote() {
+				t.Errorf("Span %s should continue the remote parent", node.name)
+			}
+			continue
+		}
+		if span.Parent.SpanID() != spans[node.parent].SpanContext.SpanID() {
+			t.Errorf("Span %s should be a child of %s", node.name, node.parent)
+		}
+	}
+
+	for service, spanName := range map[string]string{"grafana": "grafana.RenderPanel", "discord": "discord.postMessage"} {
+		spanContext := spans[spanName].SpanContext
+		want := "00-" + spanContext.TraceID().String() + "-" + spanContext.SpanID().String() + "-01"
+		if traceparents[service] != want {
+			t.Errorf("Expected the %s request to carry traceparent %s, got %q", service, want, traceparents[service])
+		}
+	}
+
+	if kind := spans["discord.postMessage"].SpanKind; kind != trace.SpanKindClient {
+		t.Errorf("Expected discord.postMessage to be a client span, got %s", kind)
+	}
+}
+
+func spanNames(spans map[string]tracetest.SpanStub) []string {
+	names := make([]string, 0, len(spans))
+	for name := range spans {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,132 @@
+package windows
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+// Registry holds the configured windows of each channel, and the ones added
+// at runtime through the admin API. The added windows are kept in memory, so
+// they are lost on restart
+type Registry struct {
+	mutex      sync.RWMutex
+	configured map[string][]Window
+	added      map[string]Window
+	nextID     int
+	now        func() time.Time
+}
+
+// Default is the Registry used by the application, replaced on startup by
+// the configured one
+var Default = NewRegistry()
+
+// NewRegistry creates a Registry without windows
+func NewRegistry() *Registry {
+	return &Registry{
+		configured: map[string][]Window{},
+		added:      map[string]Window{},
+		now:        time.Now,
+	}
+}
+
+// NewRegistryFromConfig creates the Registry with the windows of every
+// channel
+func NewRegistryFromConfig(configs config.Config) (*Registry, error) {
+	registry := NewRegistry()
+
+	for channelName, discordChannel := range configs.DiscordChannels {
+		for _, timeWindow := range discordChannel.Windows {
+			window, err := Parse(channelName, timeWindow)
+			if err != nil {
+				return nil, fmt.Errorf("windows.NewRegistryFromConfig: Channel %s: \n%+v", channelName, err)
+			}
+			registry.configured[channelName] = append(registry.configured[channelName], window)
+		}
+	}
+
+	return registry, nil
+}
+
+// Add validates and adds a window to the channel, returning it with its ID
+func (r *Registry) Add(channel string, timeWindow config.TimeWindow) (Window, error) {
+	window, err := Parse(channel, timeWindow)
+	if err != nil {
+		return Window{}, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.nextID++
+	window.ID = strconv.Itoa(r.nextID)
+	r.added[window.ID] = window
+
+	return window, nil
+}
+
+// Remove deletes an added window, telling whether it existed
+func (r *Registry) Remove(id string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, ok := r.added[id]
+	delete(r.added, id)
+	return ok
+}
+
+// List returns the windows of the channel, or of every channel when empty,
+// the configured ones first. Added windows are dropped once expired
+func (r *Registry) List(channel string) []Window {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.now()
+	windows := []Window{}
+
+	channelNames := make([]string, 0, len(r.configured))
+	for channelName := range r.configured {
+		channelNames = append(channelNames, channelName)
+	}
+	sort.Strings(channelNames)
+
+	for _, channelName := range channelNames {
+		if channel == "" || channel == channelName {
+			windows = append(windows, r.configured[channelName]...)
+		}
+	}
+
+	added := []Window{}
+	for id, window := range r.added {
+		if window.Expired(now) {
+			delete(r.added, id)
+			continue
+		}
+		if channel == "" || channel == window.Channel {
+			added = append(added, window)
+		}
+	}
+	sort.Slice(added, func(i, j int) bool {
+		first, _ := strconv.Atoi(added[i].ID)
+		second, _ := strconv.Atoi(added[j].ID)
+		return first < second
+	})
+
+	return append(windows, added...)
+}
+
+// Active returns the windows of the channel active at t that apply to a
+// message with the given severities
+func (r *Registry) Active(channel string, countBySeverity map[string]int, t time.Time) []Window {
+	active := []Window{}
+	for _, window := range r.List(channel) {
+		if window.ActiveAt(t) && window.AppliesTo(countBySeverity) {
+			active = append(active, window)
+		}
+	}
+	return active
+}
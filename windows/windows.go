@@ -0,0 +1,269 @@
+package windows
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+// Actions of a window, from the strongest to the weakest
+const (
+	ActionSuppress          = "suppress"
+	ActionStripMentions     = "stripMentions"
+	ActionDowngradeMentions = "downgradeMentions"
+)
+
+var actionStrength = map[string]int{
+	ActionSuppress:          3,
+	ActionStripMentions:     2,
+	ActionDowngradeMentions: 1,
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// Window is a parsed TimeWindow of a channel
+type Window struct {
+	config.TimeWindow
+	// ID identifies the windows added through the admin API, empty for the
+	// configured ones
+	ID      string `json:"id,omitempty"`
+	Channel string `json:"channel"`
+
+	location *time.Location
+	weekdays map[time.Weekday]bool
+	times    []minuteRange
+	start    time.Time
+	end      time.Time
+}
+
+// minuteRange is a range of minutes of the day, end excluded
+type minuteRange struct {
+	start, end int
+}
+
+// Parse validates the window of the channel
+func Parse(channel string, timeWindow config.TimeWindow) (Window, error) {
+	window := Window{TimeWindow: timeWindow, Channel: channel, location: time.UTC}
+
+	if _, ok := actionStrength[timeWindow.Action]; !ok {
+		return Window{}, fmt.Errorf("windows.Parse: Window %q: Invalid action %q", timeWindow.Name, timeWindow.Action)
+	}
+
+	if timeWindow.Timezone != "" {
+		location, err := time.LoadLocation(timeWindow.Timezone)
+		if err != nil {
+			return Window{}, fmt.Errorf("windows.Parse: Window %q: Invalid timezone \n%+v", timeWindow.Name, err)
+		}
+		window.location = location
+	}
+
+	if len(timeWindow.Weekdays) > 0 {
+		window.weekdays = map[time.Weekday]bool{}
+		for _, weekdayRange := range timeWindow.Weekdays {
+			if err := addWeekdays(window.weekdays, weekdayRange); err != nil {
+				return Window{}, fmt.Errorf("windows.Parse: Window %q: \n%+v", timeWindow.Name, err)
+			}
+		}
+	}
+
+	for _, timeRange := range timeWindow.Times {
+		parsed, err := parseTimeRange(timeRange)
+		if err != nil {
+			return Window{}, fmt.Errorf("windows.Parse: Window %q: \n%+v", timeWindow.Name, err)
+		}
+		window.times = append(window.times, parsed)
+	}
+
+	var err error
+	if window.start, err = parseDate(timeWindow.Start, window.location); err != nil {
+		return Window{}, fmt.Errorf("windows.Parse: Window %q: Invalid start \n%+v", timeWindow.Name, err)
+	}
+	if window.end, err = parseDate(timeWindow.End, window.location); err != nil {
+		return Window{}, fmt.Errorf("windows.Parse: Window %q: Invalid end \n%+v", timeWindow.Name, err)
+	}
+	if !window.start.IsZero() && !window.end.IsZero() && !window.end.After(window.start) {
+		return Window{}, fmt.Errorf("windows.Parse: Window %q: The end is not after the start", timeWindow.Name)
+	}
+
+	if window.weekdays == nil && window.times == nil && window.start.IsZero() && window.end.IsZero() {
+		return Window{}, fmt.Errorf(
+			"windows.Parse: Window %q: At least one of weekdays, times, start or end is needed", timeWindow.Name)
+	}
+
+	return window, nil
+}
+
+// ActiveAt tells whether t is within the window
+func (w Window) ActiveAt(t time.Time) bool {
+	if !w.start.IsZero() && t.Before(w.start) {
+		return false
+	}
+	if !w.end.IsZero() && !t.Before(w.end) {
+		return false
+	}
+
+	local := t.In(w.location)
+
+	if w.weekdays != nil && !w.weekdays[local.Weekday()] {
+		return false
+	}
+
+	if w.times == nil {
+		return true
+	}
+	minute := local.Hour()*60 + local.Minute()
+	for _, timeRange := range w.times {
+		if minute >= timeRange.start && minute < timeRange.end {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired tells whether the window can't be active anymore at t
+func (w Window) Expired(t time.Time) bool {
+	return !w.end.IsZero() && !t.Before(w.end)
+}
+
+// AppliesTo tells whether the window concerns a message with the given
+// severities
+func (w Window) AppliesTo(countBySeverity map[string]int) bool {
+	if len(w.Severities) == 0 {
+		return true
+	}
+
+	for severity := range countBySeverity {
+		if !contains(w.Severities, severity) {
+			return false
+		}
+	}
+	return true
+}
+
+// Strongest returns the window with the strongest action, suppress first,
+// or nil when there is none
+func Strongest(windows []Window) *Window {
+	var strongest *Window
+	for i := range windows {
+		if strongest == nil || actionStrength[windows[i].Action] > actionStrength[strongest.Action] {
+			strongest = &windows[i]
+		}
+	}
+	return strongest
+}
+
+// Describe tells the effect of the window, e.g. for previews and history
+func (w Window) Describe() string {
+	name := w.Name
+	if name == "" {
+		name = w.ID
+	}
+
+	switch w.Action {
+	case ActionSuppress:
+		return fmt.Sprintf("the window %q suppresses the message", name)
+	case ActionStripMentions:
+		return fmt.Sprintf("the window %q strips the mentions", name)
+	default:
+		return fmt.Sprintf("the window %q downgrades the mentions to %s",
+			name, strings.Join(w.RolesToMention, " "))
+	}
+}
+
+// addWeekdays adds a day, e.g. "mon", or a range of days, e.g. "mon-fri"
+func addWeekdays(days map[time.Weekday]bool, weekdayRange string) error {
+	first, last, isRange := strings.Cut(strings.ToLower(strings.TrimSpace(weekdayRange)), "-")
+	if !isRange {
+		last = first
+	}
+
+	firstDay, ok := weekdays[strings.TrimSpace(first)]
+	if !ok {
+		return fmt.Errorf("windows.addWeekdays: Invalid weekday %q", weekdayRange)
+	}
+	lastDay, ok := weekdays[strings.TrimSpace(last)]
+	if !ok {
+		return fmt.Errorf("windows.addWeekdays: Invalid weekday %q", weekdayRange)
+	}
+
+	// Ranges can wrap around the week, e.g. "fri-mon"
+	for day := firstDay; ; day = (day + 1) % 7 {
+		days[day] = true
+		if day == lastDay {
+			return nil
+		}
+	}
+}
+
+// parseTimeRange parses e.g. "09:00-18:00"
+func parseTimeRange(timeRange string) (minuteRange, error) {
+	start, end, ok := strings.Cut(timeRange, "-")
+	if !ok {
+		return minuteRange{}, fmt.Errorf("windows.parseTimeRange: Invalid range %q, expected HH:MM-HH:MM", timeRange)
+	}
+
+	startMinute, err := parseMinute(start)
+	if err != nil {
+		return minuteRange{}, err
+	}
+	endMinute, err := parseMinute(end)
+	if err != nil {
+		return minuteRange{}, err
+	}
+
+	if endMinute <= startMinute {
+		return minuteRange{}, fmt.Errorf(
+			"windows.parseTimeRange: Invalid range %q, the end must be after the start. "+
+				"Split overnight ranges in two, e.g. 22:00-24:00 and 00:00-08:00", timeRange)
+	}
+
+	return minuteRange{start: startMinute, end: endMinute}, nil
+}
+
+// parseMinute parses "HH:MM" into the minute of the day, up to "24:00"
+func parseMinute(value string) (int, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(value), ":")
+	hour, hourErr := strconv.Atoi(hours)
+	minute, minuteErr := strconv.Atoi(minutes)
+
+	if !ok || hourErr != nil || minuteErr != nil ||
+		hour < 0 || minute < 0 || minute > 59 || hour*60+minute > 24*60 {
+		return 0, fmt.Errorf("windows.parseMinute: Invalid time %q, expected HH:MM", value)
+	}
+
+	return hour*60 + minute, nil
+}
+
+// parseDate parses RFC 3339, or "2006-01-02 15:04" in the location. Empty
+// values are zero
+func parseDate(value string, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	return time.ParseInLocation("2006-01-02 15:04", value, location)
+}
+
+func contains(s []string, str string) bool {
+	for _, v := range s {
+		if v == str {
+			return true
+		}
+	}
+	return false
+}
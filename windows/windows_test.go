@@ -0,0 +1,174 @@
+package windows
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kolesaev/alertmanager-discord/config"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeWindow config.TimeWindow
+		wantErr    bool
+	}{
+		{"weekdays and times", config.TimeWindow{Action: ActionSuppress, Weekdays: []string{"mon-fri"}, Times: []string{"22:00-24:00"}}, false},
+		{"one-off", config.TimeWindow{Action: ActionStripMentions, Start: "2026-01-01 10:00", End: "2026-01-01T12:00:00Z"}, false},
+		{"open end", config.TimeWindow{Action: ActionStripMentions, Start: "2026-01-01 10:00"}, false},
+		{"no condition", config.TimeWindow{Action: ActionSuppress}, true},
+		{"invalid action", config.TimeWindow{Action: "mute", Weekdays: []string{"sat"}}, true},
+		{"invalid weekday", config.TimeWindow{Action: ActionSuppress, Weekdays: []string{"mon-fry"}}, true},
+		{"invalid timezone", config.TimeWindow{Action: ActionSuppress, Weekdays: []string{"sat"}, Timezone: "Mars/Olympus"}, true},
+		{"overnight range", config.TimeWindow{Action: ActionSuppress, Times: []string{"22:00-08:00"}}, true},
+		{"invalid time", config.TimeWindow{Action: ActionSuppress, Times: []string{"09:60-10:00"}}, true},
+		{"after midnight", config.TimeWindow{Action: ActionSuppress, Times: []string{"23:00-24:01"}}, true},
+		{"end before start", config.TimeWindow{Action: ActionSuppress, Start: "2026-01-02 00:00", End: "2026-01-01 00:00"}, true},
+		{"invalid start", config.TimeWindow{Action: ActionSuppress, Start: "tomorrow"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse("default", test.timeWindow)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestActiveAt(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("No time zone database")
+	}
+
+	nights := config.TimeWindow{
+		Action:   ActionStripMentions,
+		Weekdays: []string{"mon-fri"},
+		Times:    []string{"22:00-24:00", "00:00-08:00"},
+		Timezone: "Europe/Berlin",
+	}
+	weekend := config.TimeWindow{Action: ActionSuppress, Weekdays: []string{"sat", "Sunday"}}
+	wrapping := config.TimeWindow{Action: ActionSuppress, Weekdays: []string{"fri-mon"}}
+	maintenance := config.TimeWindow{
+		Action:   ActionSuppress,
+		Start:    "2026-03-10 09:00",
+		End:      "2026-03-10 11:00",
+		Timezone: "Europe/Berlin",
+	}
+
+	tests := []struct {
+		name       string
+		timeWindow config.TimeWindow
+		time       time.Time
+		want       bool
+	}{
+		// 2026-03-10 is a Tuesday
+		{"night, local time", nights, time.Date(2026, 3, 10, 23, 30, 0, 0, berlin), true},
+		{"early morning", nights, time.Date(2026, 3, 10, 7, 59, 0, 0, berlin), true},
+		{"end excluded", nights, time.Date(2026, 3, 10, 8, 0, 0, 0, berlin), false},
+		{"day", nights, time.Date(2026, 3, 10, 12, 0, 0, 0, berlin), false},
+		{"night in UTC", nights, time.Date(2026, 3, 10, 21, 30, 0, 0, time.UTC), true},
+		{"night in UTC, day locally", nights, time.Date(2026, 3, 10, 7, 30, 0, 0, time.UTC), false},
+		{"saturday night", nights, time.Date(2026, 3, 14, 23, 0, 0, 0, berlin), false},
+		{"saturday", weekend, time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC), true},
+		{"sunday", weekend, time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC), true},
+		{"monday", weekend, time.Date(2026, 3, 16, 12, 0, 0, 0, time.UTC), false},
+		{"range wrapping the week", wrapping, time.Date(2026, 3, 16, 12, 0, 0, 0, time.UTC), true},
+		{"outside wrapping range", wrapping, time.Date(2026, 3, 11, 12, 0, 0, 0, time.UTC), false},
+		{"during maintenance", maintenance, time.Date(2026, 3, 10, 9, 0, 0, 0, berlin), true},
+		{"before maintenance", maintenance, time.Date(2026, 3, 10, 7, 59, 0, 0, time.UTC), false},
+		{"after maintenance", maintenance, time.Date(2026, 3, 10, 11, 0, 0, 0, berlin), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			window, err := Parse("default", test.timeWindow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := window.ActiveAt(test.time); got != test.want {
+				t.Errorf("ActiveAt(%s) = %v, want %v", test.time, got, test.want)
+			}
+		})
+	}
+}
+
+func TestAppliesTo(t *testing.T) {
+	warnings := Window{TimeWindow: config.TimeWindow{Severities: []string{"warning", "info"}}}
+
+	tests := []struct {
+		name            string
+		window          Window
+		countBySeverity map[string]int
+		want            bool
+	}{
+		{"any severity", Window{}, map[string]int{"critical": 1}, true},
+		{"listed severities", warnings, map[string]int{"warning": 2, "info": 1}, true},
+		{"one other severity", warnings, map[string]int{"warning": 2, "critical": 1}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.window.AppliesTo(test.countBySeverity); got != test.want {
+				t.Errorf("AppliesTo() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStrongest(t *testing.T) {
+	window := func(action string) Window {
+		return Window{TimeWindow: config.TimeWindow{Name: action, Action: action}}
+	}
+
+	tests := []struct {
+		name    string
+		windows []Window
+		want    string
+	}{
+		{"none", nil, ""},
+		{"single", []Window{window(ActionDowngradeMentions)}, ActionDowngradeMentions},
+		{"suppress wins", []Window{window(ActionStripMentions), window(ActionSuppress), window(ActionDowngradeMentions)}, ActionSuppress},
+		{"strip over downgrade", []Window{window(ActionDowngradeMentions), window(ActionStripMentions)}, ActionStripMentions},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			strongest := Strongest(test.windows)
+			got := ""
+			if strongest != nil {
+				got = strongest.Action
+			}
+			if got != test.want {
+				t.Errorf("Strongest() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRegistryExpiresAddedWindows(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	registry := NewRegistry()
+	registry.now = func() time.Time { return now }
+
+	window, err := registry.Add("default", config.TimeWindow{Action: ActionSuppress, End: "2026-03-10T13:00:00Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active := registry.Active("default", nil, now); len(active) != 1 || active[0].ID != window.ID {
+		t.Fatalf("Active() = %v, want the added window", active)
+	}
+	if active := registry.Active("other", nil, now); len(active) != 0 {
+		t.Errorf("Active() for another channel = %v", active)
+	}
+
+	now = now.Add(time.Hour)
+	if windows := registry.List(""); len(windows) != 0 {
+		t.Errorf("List() = %v, want the expired window dropped", windows)
+	}
+	if registry.Remove(window.ID) {
+		t.Errorf("Remove() found the expired window")
+	}
+}